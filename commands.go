@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/config"
+	"3code/database"
+	"3code/server"
+)
+
+const usage = `Использование:
+  3code [флаги]                         запустить сервер
+  3code [флаги] migrate status          показать состояние миграций
+  3code [флаги] migrate up [N]          применить N (по умолчанию все) миграций
+  3code [флаги] migrate down [-force] [N]
+                                        откатить N (по умолчанию одну) миграций; откат первой миграции
+                                        удаляет таблицу задач со всеми данными и выполняется только с -force
+  3code [флаги] import ics [-dry-run] ФАЙЛ
+                                        добавить задачи из календаря iCalendar (ФАЙЛ "-" - стандартный ввод);
+                                        с -dry-run только показать, что будет добавлено
+  3code [флаги] backup                  создать резервную копию базы данных в TODO_BACKUP_DIR
+  3code [флаги] backup list             показать резервные копии
+  3code [флаги] restore ФАЙЛ            восстановить базу данных из копии (путь или имя файла в TODO_BACKUP_DIR);
+                                        работает только при остановленном сервере, текущая база сохраняется
+                                        с суффиксом .before-restore
+
+Флаги имеют приоритет над переменными окружения, а те - над файлами .env. Список флагов: 3code -h`
+
+// errUsage оборачивает ошибки в аргументах командной строки.
+var errUsage = errors.New("неверные аргументы")
+
+// runCommand выполняет служебную команду, переданную в аргументах командной строки.
+func runCommand(cfg config.Config, name string, args []string) error {
+	switch name {
+	case "migrate":
+		return runMigrate(cfg.Database, args)
+	case "import":
+		return runImport(cfg.Database, args)
+	case "backup":
+		return runBackup(cfg.Database, args)
+	case "restore":
+		return runRestore(cfg.Database, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	}
+	return fmt.Errorf("%w: неизвестная команда %q\n%s", errUsage, name, usage)
+}
+
+// runMigrate выполняет команду migrate status|up|down [-force] [N].
+func runMigrate(cfg config.Database, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: не указано действие миграции\n%s", errUsage, usage)
+	}
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	force := fs.Bool("force", false, "разрешить откат первой миграции, который удаляет все задачи")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w: %v\n%s", errUsage, err, usage)
+	}
+
+	// Количество шагов необязательно: для up по умолчанию все, для down - одна миграция
+	steps := 0
+	if args[0] == "down" {
+		steps = 1
+	}
+	if fs.NArg() > 0 {
+		arg := fs.Arg(0)
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%w: некорректное количество миграций %q", errUsage, arg)
+		}
+		steps = n
+
+		// Флаг -force допускается и после количества: migrate down 2 -force
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return fmt.Errorf("%w: %v\n%s", errUsage, err, usage)
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("%w: лишние аргументы %q\n%s", errUsage, fs.Args(), usage)
+		}
+	}
+
+	// Как и сервер, команды, открывающие базу, не дают restore заменить ее файл, пока работают
+	release, err := database.HoldDatabase(cfg.File)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// Миграции здесь не применяются автоматически, поэтому открываем базу напрямую
+	dbFile, err := database.PrepareDatabaseFile(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := database.OpenDatabase(dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "status":
+		statuses, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "не применена"
+			if st.Applied {
+				state = "применена " + st.AppliedAt
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+		return nil
+
+	case "up":
+		applied, err := database.MigrateUp(db, steps)
+		fmt.Fprintf(os.Stdout, "Применено миграций: %d\n", applied)
+		return err
+
+	case "down":
+		if !*force {
+			statuses, err := database.GetMigrationStatus(db)
+			if err != nil {
+				return err
+			}
+			if revertsBaseline(statuses, steps) {
+				return fmt.Errorf("%w: откат первой миграции удалит таблицу задач со всеми данными, "+
+					"сделайте резервную копию (3code backup) и повторите команду с -force", errUsage)
+			}
+		}
+		reverted, err := database.MigrateDown(db, steps)
+		fmt.Fprintf(os.Stdout, "Откачено миграций: %d\n", reverted)
+		return err
+	}
+
+	return fmt.Errorf("%w: неизвестное действие миграции %q\n%s", errUsage, args[0], usage)
+}
+
+// revertsBaseline сообщает, дойдет ли откат steps последних примененных миграций до первой, которая создает таблицу задач.
+func revertsBaseline(statuses []database.MigrationStatus, steps int) bool {
+	for i := len(statuses) - 1; i >= 0 && steps > 0; i-- {
+		if !statuses[i].Applied {
+			continue
+		}
+		if i == 0 {
+			return true
+		}
+		steps--
+	}
+	return false
+}
+
+// runImport выполняет команду import ics [-dry-run] ФАЙЛ.
+func runImport(cfg config.Database, args []string) error {
+	if len(args) == 0 || args[0] != "ics" {
+		return fmt.Errorf("%w: укажите формат импорта: ics\n%s", errUsage, usage)
+	}
+
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "только показать, что будет добавлено")
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w: %v\n%s", errUsage, err, usage)
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%w: укажите один файл календаря\n%s", errUsage, usage)
+	}
+
+	in := os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("функция runImport: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	release, err := database.HoldDatabase(cfg.File)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	db, err := database.SetupDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := server.ImportICS(context.Background(), database.NewSQLiteStore(db), in, time.Now(), *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range report.Tasks {
+		fmt.Fprintf(os.Stdout, "%s\t%s\t%s\n", task.Date, task.Repeat, task.Title)
+	}
+	for _, item := range report.Skipped {
+		fmt.Fprintf(os.Stdout, "пропущено: строка %d: %q: %s\n", item.Line, item.Summary, item.Reason)
+	}
+	if report.DryRun {
+		fmt.Fprintf(os.Stdout, "Пробный запуск, будет добавлено задач: %d, пропущено: %d\n", report.Imported, len(report.Skipped))
+	} else {
+		fmt.Fprintf(os.Stdout, "Добавлено задач: %d, пропущено: %d\n", report.Imported, len(report.Skipped))
+	}
+	return nil
+}
+
+// runBackup выполняет команду backup [list].
+func runBackup(cfg config.Database, args []string) error {
+	if len(args) > 0 {
+		if args[0] != "list" || len(args) > 1 {
+			return fmt.Errorf("%w: неизвестное действие резервного копирования %q\n%s", errUsage, strings.Join(args, " "), usage)
+		}
+		backups, err := database.ListBackups(cfg.BackupDir)
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			fmt.Fprintf(os.Stdout, "%s\t%s\t%d\n", b.Name, b.Created.Format(time.DateTime), b.Size)
+		}
+		return nil
+	}
+
+	release, err := database.HoldDatabase(cfg.File)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	db, err := database.SetupDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	backup, err := database.NewBackups(db, cfg.BackupDir, cfg.BackupKeep).Create(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "Создана резервная копия: %s\n", backup.Path)
+	return nil
+}
+
+// runRestore выполняет команду restore ФАЙЛ. ФАЙЛ - путь к копии или имя файла в директории резервных копий.
+func runRestore(cfg config.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%w: укажите одну резервную копию\n%s", errUsage, usage)
+	}
+
+	backup := args[0]
+	if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) && filepath.Base(backup) == backup {
+		backup = filepath.Join(cfg.BackupDir, backup)
+	}
+
+	saved, err := database.RestoreBackup(backup, cfg.File)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "База данных %s восстановлена из %s\n", cfg.File, backup)
+	if saved != "" {
+		fmt.Fprintf(os.Stdout, "Прежняя база данных сохранена в %s\n", saved)
+	}
+	return nil
+}
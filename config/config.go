@@ -0,0 +1,448 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Ошибки конфигурации. Возвращаются обернутыми, проверять их нужно через errors.Is.
+var (
+	// ErrConfigMissing - не найден явно указанный файл конфигурации или не задана обязательная настройка.
+	ErrConfigMissing = errors.New("не задана обязательная настройка")
+	// ErrConfigInvalid - настройка задана, но ее значение некорректно.
+	ErrConfigInvalid = errors.New("некорректное значение настройки")
+)
+
+// DefaultEnvFiles - файлы .env, которые читаются, если -env-file не указан. Отсутствие этих файлов не ошибка.
+var DefaultEnvFiles = []string{"./02_env/server.env", "./02_env/db.env"}
+
+// Config - вся конфигурация приложения.
+type Config struct {
+	Server   Server
+	Database Database
+	Logger   Logger
+
+	// Строковые значения настроек и количество значений из каждого вида источника - для логов
+	values  map[string]string
+	sources map[string]int
+}
+
+// Server - настройки HTTP-сервера.
+type Server struct {
+	Port        string
+	FrontendDir string
+
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	// Сколько ждать между переводом /readyz в состояние "не готов" и началом остановки,
+	// чтобы балансировщик успел перестать присылать запросы
+	DrainDelay time.Duration
+
+	// Пароль для входа и секрет подписи токенов. Пустой пароль отключает аутентификацию
+	Password  string
+	JWTSecret string
+	TokenTTL  time.Duration
+
+	// Максимальное количество задач в ответе GET /api/tasks
+	TasksLimit int
+
+	// TLS. Сертификат берется из файлов TLSCertFile и TLSKeyFile, а в режиме разработки (TLSDev)
+	// создается самоподписанный сертификат, который хранится в TLSDevDir
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSMinVersion string // 1.2 или 1.3
+	TLSCiphers    string // modern - только ECDHE с AEAD, default - набор Go по умолчанию
+	TLSDev        bool
+	TLSDevDir     string
+	HTTP2         bool
+	// Порт, на котором HTTP-запросы перенаправляются на HTTPS. Пустая строка - перенаправление не нужно
+	RedirectPort string
+}
+
+// TLSEnabled сообщает, обслуживает ли сервер запросы по HTTPS.
+func (s Server) TLSEnabled() bool {
+	return s.TLSCertFile != "" || s.TLSDev
+}
+
+// Database - настройки базы данных.
+type Database struct {
+	File     string
+	Attempts int // Количество попыток проверить файл базы данных
+
+	BackupDir      string        // Директория для резервных копий
+	BackupInterval time.Duration // Интервал автоматического резервного копирования, 0 - отключено
+	BackupKeep     int           // Сколько последних резервных копий хранить
+}
+
+// Logger - настройки логирования.
+type Logger struct {
+	Dir    string
+	Level  slog.Level // debug, info, warn или error
+	Format string     // text или json
+
+	// Ротация и хранение файлов логов
+	MaxSizeMB  int64 // Размер файла в мегабайтах, после которого начинается новый файл
+	MaxFiles   int   // Сколько файлов логов хранить
+	MaxAgeDays int   // Сколько дней хранить файлы логов
+	Compress   bool  // Сжимать ли старые файлы логов в .gz
+}
+
+// option описывает одну настройку: переменную окружения, соответствующий ей флаг и значение по умолчанию.
+type option struct {
+	env    string
+	flag   string // Пустая строка - настройку нельзя задать флагом
+	def    string
+	usage  string
+	secret bool // Значение не выводится в лог
+}
+
+// shown возвращает значение настройки для лога: секреты скрываются.
+func (o option) shown(value string) string {
+	if o.secret && value != "" {
+		return "***"
+	}
+	return value
+}
+
+// options - все известные настройки. Имена переменных окружения совпадают с теми, что уже используются в 02_env.
+var options = []option{
+	{env: "TODO_PORT_7540", flag: "port", def: "7540", usage: "порт HTTP-сервера"},
+	{env: "TODO_FRONTEND_DIR", flag: "frontend-dir", def: "./web", usage: "директория с файлами фронтенда"},
+	{env: "TIME_UNIT", flag: "time-unit", def: "s", usage: "единица измерения для таймаутов, заданных числом"},
+	{env: "SERVER_READ_TIME", flag: "read-time", def: "10", usage: "таймаут чтения запроса"},
+	{env: "SERVER_WRITE_TIME", flag: "write-time", def: "10", usage: "таймаут записи ответа"},
+	{env: "SERVER_IDLE_TIME", flag: "idle-time", def: "120", usage: "таймаут простоя keep-alive соединения"},
+	{env: "CTX_TIMEOUT", flag: "ctx-timeout", def: "5", usage: "таймаут корректного завершения работы сервера"},
+	{env: "TODO_DRAIN_DELAY", flag: "drain-delay", def: "0", usage: "пауза перед остановкой, чтобы балансировщик убрал сервер из работы"},
+	{env: "TODO_PASSWORD", def: "", usage: "пароль для входа", secret: true},
+	{env: "TODO_JWT_SECRET", def: "", usage: "секрет подписи токенов", secret: true},
+	{env: "TOKEN_TTL", flag: "token-ttl", def: "28800", usage: "срок действия токена"},
+	{env: "TODO_TASKS_LIMIT", flag: "tasks-limit", def: "50", usage: "максимальное количество задач в списке"},
+	{env: "TODO_TLS_CERT", flag: "tls-cert", def: "", usage: "файл сертификата TLS в формате PEM"},
+	{env: "TODO_TLS_KEY", flag: "tls-key", def: "", usage: "файл закрытого ключа TLS в формате PEM"},
+	{env: "TODO_TLS_MIN_VERSION", flag: "tls-min-version", def: "1.2", usage: "минимальная версия TLS: 1.2 или 1.3"},
+	{env: "TODO_TLS_CIPHERS", flag: "tls-ciphers", def: "modern", usage: "набор шифров TLS 1.2: modern или default"},
+	{env: "TODO_TLS_DEV", flag: "tls-dev", def: "false", usage: "создать самоподписанный сертификат для разработки"},
+	{env: "TODO_TLS_DEV_DIR", flag: "tls-dev-dir", def: "./03_certs", usage: "директория для самоподписанного сертификата"},
+	{env: "TODO_HTTP2", flag: "http2", def: "true", usage: "включить HTTP/2 при работе по TLS"},
+	{env: "TODO_HTTP_REDIRECT_PORT", flag: "http-redirect-port", def: "", usage: "порт для перенаправления HTTP на HTTPS"},
+	{env: "TODO_DBFILE", flag: "db-file", def: "./db/scheduler.db", usage: "путь к файлу базы данных"},
+	{env: "TODO_ATTEMPTS", flag: "db-attempts", def: "3", usage: "количество попыток доступа к файлу базы данных"},
+	{env: "TODO_BACKUP_DIR", flag: "backup-dir", def: "./04_backups", usage: "директория для резервных копий базы данных"},
+	{env: "TODO_BACKUP_INTERVAL", flag: "backup-interval", def: "0", usage: "интервал автоматического резервного копирования, 0 - отключено"},
+	{env: "TODO_BACKUP_KEEP", flag: "backup-keep", def: "7", usage: "сколько последних резервных копий хранить"},
+	{env: "TODO_LOG_DIR", flag: "log-dir", def: "./09_logs", usage: "директория для файлов логов"},
+	{env: "TODO_LOG_LEVEL", flag: "log-level", def: "info", usage: "уровень логирования: debug, info, warn или error"},
+	{env: "TODO_LOG_FORMAT", flag: "log-format", def: "text", usage: "формат логов: text или json"},
+	{env: "TODO_LOG_MAX_SIZE", flag: "log-max-size", def: "10", usage: "размер файла логов в мегабайтах, после которого начинается новый файл"},
+	{env: "TODO_LOG_MAX_FILES", flag: "log-max-files", def: "10", usage: "сколько файлов логов хранить"},
+	{env: "TODO_LOG_MAX_AGE", flag: "log-max-age", def: "30", usage: "сколько дней хранить файлы логов"},
+	{env: "TODO_LOG_COMPRESS", flag: "log-compress", def: "false", usage: "сжимать ли старые файлы логов в .gz"},
+}
+
+// Load собирает конфигурацию из флагов args, переменных окружения, файлов .env и значений по умолчанию
+// (в порядке убывания приоритета) и проверяет ее. Возвращает аргументы, оставшиеся после флагов.
+// Все найденные ошибки возвращаются вместе, а не только первая.
+func Load(args []string) (Config, []string, error) {
+	fs := flag.NewFlagSet("3code", flag.ContinueOnError)
+	envFiles := fs.String("env-file", "", "файлы .env через запятую (по умолчанию "+strings.Join(DefaultEnvFiles, ",")+")")
+
+	flagValues := make(map[string]*string)
+	for _, o := range options {
+		if o.flag != "" {
+			flagValues[o.env] = fs.String(o.flag, "", fmt.Sprintf("%s (%s, по умолчанию %q)", o.usage, o.env, o.def))
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return Config{}, nil, err
+		}
+		return Config{}, nil, fmt.Errorf("%w: %w", ErrConfigInvalid, err)
+	}
+
+	// Запоминаем, какие флаги заданы явно: пустое значение флага тоже может быть осознанным
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	fileValues, err := readEnvFiles(*envFiles, setFlags["env-file"])
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	values := make(map[string]string, len(options))
+	sources := make(map[string]int)
+	for _, o := range options {
+		// kind - вид источника для сводки в логе, source - точный источник для отладки
+		value, source, kind := o.def, "по умолчанию", "defaults"
+		if v, ok := fileValues[o.env]; ok {
+			value, source, kind = v, "файл .env", "files"
+		}
+		if v, ok := os.LookupEnv(o.env); ok {
+			value, source, kind = v, "переменная окружения", "env"
+		}
+		if o.flag != "" && setFlags[o.flag] {
+			value, source, kind = *flagValues[o.env], "флаг -"+o.flag, "flags"
+		}
+		values[o.env] = value
+		sources[kind]++
+		slog.Debug("Настройка загружена", "name", o.env, "value", o.shown(value), "source", source)
+	}
+
+	p := parser{values: values}
+	unit := values["TIME_UNIT"]
+
+	cfg := Config{
+		Server: Server{
+			Port:            values["TODO_PORT_7540"],
+			FrontendDir:     values["TODO_FRONTEND_DIR"],
+			ReadTimeout:     p.duration("SERVER_READ_TIME", unit),
+			WriteTimeout:    p.duration("SERVER_WRITE_TIME", unit),
+			IdleTimeout:     p.duration("SERVER_IDLE_TIME", unit),
+			ShutdownTimeout: p.duration("CTX_TIMEOUT", unit),
+			DrainDelay:      p.duration("TODO_DRAIN_DELAY", unit),
+			Password:        values["TODO_PASSWORD"],
+			JWTSecret:       values["TODO_JWT_SECRET"],
+			TokenTTL:        p.duration("TOKEN_TTL", unit),
+			TasksLimit:      p.int("TODO_TASKS_LIMIT"),
+			TLSCertFile:     values["TODO_TLS_CERT"],
+			TLSKeyFile:      values["TODO_TLS_KEY"],
+			TLSMinVersion:   values["TODO_TLS_MIN_VERSION"],
+			TLSCiphers:      strings.ToLower(values["TODO_TLS_CIPHERS"]),
+			TLSDev:          p.bool("TODO_TLS_DEV"),
+			TLSDevDir:       values["TODO_TLS_DEV_DIR"],
+			HTTP2:           p.bool("TODO_HTTP2"),
+			RedirectPort:    values["TODO_HTTP_REDIRECT_PORT"],
+		},
+		Database: Database{
+			File:           values["TODO_DBFILE"],
+			Attempts:       p.int("TODO_ATTEMPTS"),
+			BackupDir:      values["TODO_BACKUP_DIR"],
+			BackupInterval: p.duration("TODO_BACKUP_INTERVAL", unit),
+			BackupKeep:     p.int("TODO_BACKUP_KEEP"),
+		},
+		Logger: Logger{
+			Dir:        values["TODO_LOG_DIR"],
+			Level:      p.level("TODO_LOG_LEVEL"),
+			Format:     strings.ToLower(values["TODO_LOG_FORMAT"]),
+			MaxSizeMB:  int64(p.int("TODO_LOG_MAX_SIZE")),
+			MaxFiles:   p.int("TODO_LOG_MAX_FILES"),
+			MaxAgeDays: p.int("TODO_LOG_MAX_AGE"),
+			Compress:   p.bool("TODO_LOG_COMPRESS"),
+		},
+	}
+
+	if err := errors.Join(append(p.errs, cfg.Validate())...); err != nil {
+		return Config{}, nil, err
+	}
+	cfg.values, cfg.sources = values, sources
+	return cfg, fs.Args(), nil
+}
+
+// LogSummary пишет в лог одну сводку о загруженных настройках: сколько значений взято из каждого источника.
+// Значение каждой настройки с источником пишется на уровне Debug в Load.
+func (c Config) LogSummary() {
+	slog.Info("Настройки загружены", "options", len(c.values), "defaults", c.sources["defaults"],
+		"files", c.sources["files"], "env", c.sources["env"], "flags", c.sources["flags"])
+}
+
+// LogChanges пишет в лог только настройки, которые изменились по сравнению с prev, например при перезагрузке по SIGHUP.
+// Прежние настройки хранит вызывающий, поэтому независимые конфигурации в одном процессе не мешают друг другу.
+func (c Config) LogChanges(prev Config) {
+	changed := 0
+	for _, o := range options {
+		if prev.values[o.env] != c.values[o.env] {
+			changed++
+			slog.Info("Настройка изменена", "name", o.env, "old", o.shown(prev.values[o.env]), "new", o.shown(c.values[o.env]))
+		}
+	}
+	if changed == 0 {
+		slog.Info("Настройки перечитаны, изменений нет")
+	}
+}
+
+// Validate проверяет согласованность значений и возвращает все найденные ошибки разом.
+func (c Config) Validate() error {
+	var errs []error
+	missing := func(env string) {
+		errs = append(errs, fmt.Errorf("%w: %s не может быть пустым", ErrConfigMissing, env))
+	}
+	positive := func(env string, ok bool) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s должно быть больше нуля", ErrConfigInvalid, env))
+		}
+	}
+
+	if c.Server.Port == "" {
+		missing("TODO_PORT_7540")
+	} else if !validPort(c.Server.Port) {
+		errs = append(errs, fmt.Errorf("%w: TODO_PORT_7540 должен быть числом от 1 до 65535, получено %q", ErrConfigInvalid, c.Server.Port))
+	}
+	if c.Server.FrontendDir == "" {
+		missing("TODO_FRONTEND_DIR")
+	}
+	positive("SERVER_READ_TIME", c.Server.ReadTimeout > 0)
+	positive("SERVER_WRITE_TIME", c.Server.WriteTimeout > 0)
+	positive("SERVER_IDLE_TIME", c.Server.IdleTimeout > 0)
+	positive("CTX_TIMEOUT", c.Server.ShutdownTimeout > 0)
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("%w: TODO_DRAIN_DELAY не может быть отрицательным", ErrConfigInvalid))
+	}
+	positive("TOKEN_TTL", c.Server.TokenTTL > 0)
+	// Без отдельного секрета токены пришлось бы подписывать паролем, и его можно было бы подобрать по токену
+	if c.Server.Password != "" && c.Server.JWTSecret == "" {
+		errs = append(errs, fmt.Errorf("%w: TODO_JWT_SECRET обязателен, если задан TODO_PASSWORD", ErrConfigMissing))
+	}
+	positive("TODO_TASKS_LIMIT", c.Server.TasksLimit > 0)
+
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("%w: TODO_TLS_CERT и TODO_TLS_KEY задаются только вместе", ErrConfigMissing))
+	}
+	if c.Server.TLSDev && c.Server.TLSCertFile != "" {
+		errs = append(errs, fmt.Errorf("%w: TODO_TLS_DEV нельзя включать вместе с TODO_TLS_CERT", ErrConfigInvalid))
+	}
+	if c.Server.TLSDev && c.Server.TLSDevDir == "" {
+		missing("TODO_TLS_DEV_DIR")
+	}
+	if c.Server.TLSMinVersion != "1.2" && c.Server.TLSMinVersion != "1.3" {
+		errs = append(errs, fmt.Errorf("%w: TODO_TLS_MIN_VERSION должен быть 1.2 или 1.3, получено %q", ErrConfigInvalid, c.Server.TLSMinVersion))
+	}
+	if c.Server.TLSCiphers != "modern" && c.Server.TLSCiphers != "default" {
+		errs = append(errs, fmt.Errorf("%w: TODO_TLS_CIPHERS должен быть modern или default, получено %q", ErrConfigInvalid, c.Server.TLSCiphers))
+	}
+	if c.Server.RedirectPort != "" {
+		switch {
+		case !validPort(c.Server.RedirectPort):
+			errs = append(errs, fmt.Errorf("%w: TODO_HTTP_REDIRECT_PORT должен быть числом от 1 до 65535, получено %q", ErrConfigInvalid, c.Server.RedirectPort))
+		case !c.Server.TLSEnabled():
+			errs = append(errs, fmt.Errorf("%w: TODO_HTTP_REDIRECT_PORT имеет смысл только при включенном TLS", ErrConfigInvalid))
+		case c.Server.RedirectPort == c.Server.Port:
+			errs = append(errs, fmt.Errorf("%w: TODO_HTTP_REDIRECT_PORT должен отличаться от TODO_PORT_7540", ErrConfigInvalid))
+		}
+	}
+
+	if c.Database.File == "" {
+		missing("TODO_DBFILE")
+	}
+	positive("TODO_ATTEMPTS", c.Database.Attempts > 0)
+	if c.Database.BackupDir == "" {
+		missing("TODO_BACKUP_DIR")
+	}
+	if c.Database.BackupInterval < 0 {
+		errs = append(errs, fmt.Errorf("%w: TODO_BACKUP_INTERVAL не может быть отрицательным", ErrConfigInvalid))
+	}
+	positive("TODO_BACKUP_KEEP", c.Database.BackupKeep > 0)
+
+	if c.Logger.Dir == "" {
+		missing("TODO_LOG_DIR")
+	}
+	if c.Logger.Format != "text" && c.Logger.Format != "json" {
+		errs = append(errs, fmt.Errorf("%w: TODO_LOG_FORMAT должен быть text или json, получено %q", ErrConfigInvalid, c.Logger.Format))
+	}
+	positive("TODO_LOG_MAX_SIZE", c.Logger.MaxSizeMB > 0)
+	positive("TODO_LOG_MAX_FILES", c.Logger.MaxFiles > 0)
+	positive("TODO_LOG_MAX_AGE", c.Logger.MaxAgeDays > 0)
+
+	return errors.Join(errs...)
+}
+
+// validPort проверяет, что port - номер порта от 1 до 65535.
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
+
+// readEnvFiles читает значения из файлов .env, не изменяя окружение процесса.
+// Файлы из DefaultEnvFiles необязательны, а явно указанные через -env-file должны существовать.
+func readEnvFiles(list string, explicit bool) (map[string]string, error) {
+	files := DefaultEnvFiles
+	if explicit {
+		files = strings.Split(list, ",")
+	}
+
+	values := make(map[string]string)
+	for _, file := range files {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+
+		fileValues, err := godotenv.Read(file)
+		if err != nil {
+			if !explicit && errors.Is(err, os.ErrNotExist) {
+				slog.Info("Файл с настройками не найден, используются остальные источники настроек", "file", file)
+				continue
+			}
+			return nil, fmt.Errorf("%w: ошибка чтения файла %s: %w", ErrConfigMissing, file, err)
+		}
+		slog.Debug("Файл с настройками успешно загружен", "file", file)
+
+		// Более поздние файлы переопределяют значения из более ранних
+		for k, v := range fileValues {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// parser разбирает строковые значения настроек и накапливает ошибки, чтобы вернуть их все сразу.
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+// duration разбирает длительность. Число без единицы измерения дополняется единицей unit (TIME_UNIT),
+// как это было принято в файлах 02_env, а запись вида 1m30s принимается как есть.
+func (p *parser) duration(env, unit string) time.Duration {
+	value := p.values[env]
+	if _, err := strconv.Atoi(value); err == nil {
+		value += unit
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%w: %s=%q: %w", ErrConfigInvalid, env, p.values[env], err))
+		return 0
+	}
+	return d
+}
+
+// level разбирает уровень логирования: debug, info, warn или error.
+func (p *parser) level(env string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(p.values[env])); err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%w: %s=%q: ожидается debug, info, warn или error", ErrConfigInvalid, env, p.values[env]))
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// int разбирает целое число.
+func (p *parser) int(env string) int {
+	n, err := strconv.Atoi(p.values[env])
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%w: %s=%q: ожидается целое число", ErrConfigInvalid, env, p.values[env]))
+		return 0
+	}
+	return n
+}
+
+// bool разбирает логическое значение: true/false, 1/0 и т.п.
+func (p *parser) bool(env string) bool {
+	b, err := strconv.ParseBool(p.values[env])
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%w: %s=%q: ожидается true или false", ErrConfigInvalid, env, p.values[env]))
+		return false
+	}
+	return b
+}
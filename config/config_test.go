@@ -0,0 +1,129 @@
+package config_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"3code/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeEnvFile создает временный файл .env с указанным содержимым.
+func writeEnvFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "test.env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	envFile := writeEnvFile(t, "TODO_PORT_7540=7000\nTODO_FRONTEND_DIR=./file-web\nTODO_DBFILE=./file.db\nSERVER_READ_TIME=3\nTIME_UNIT=m\n")
+	t.Setenv("TODO_PORT_7540", "7100")
+	t.Setenv("TODO_FRONTEND_DIR", "./env-web")
+
+	cfg, args, err := config.Load([]string{"-env-file", envFile, "-port", "7200", "migrate", "status"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"migrate", "status"}, args)
+	assert.Equal(t, "7200", cfg.Server.Port, "флаг важнее переменной окружения")
+	assert.Equal(t, "./env-web", cfg.Server.FrontendDir, "переменная окружения важнее файла")
+	assert.Equal(t, "./file.db", cfg.Database.File, "файл важнее значения по умолчанию")
+	assert.Equal(t, 3*time.Minute, cfg.Server.ReadTimeout, "число дополняется единицей TIME_UNIT")
+	assert.Equal(t, 10*time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, 3, cfg.Database.Attempts)
+	assert.Zero(t, cfg.Database.BackupInterval, "автоматическое резервное копирование по умолчанию отключено")
+	assert.Equal(t, 7, cfg.Database.BackupKeep)
+	assert.Equal(t, "./09_logs", cfg.Logger.Dir)
+	assert.Equal(t, int64(10), cfg.Logger.MaxSizeMB)
+	assert.False(t, cfg.Logger.Compress)
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	envFile := writeEnvFile(t, "")
+	t.Setenv("TODO_PASSWORD", "секрет")
+
+	_, _, err := config.Load([]string{
+		"-env-file", envFile,
+		"-port", "http",
+		"-read-time", "скоро",
+		"-tasks-limit", "0",
+		"-db-file", "",
+		"-log-compress", "иногда",
+	})
+	require.Error(t, err)
+
+	assert.ErrorIs(t, err, config.ErrConfigInvalid)
+	assert.ErrorIs(t, err, config.ErrConfigMissing)
+	for _, name := range []string{"TODO_PORT_7540", "SERVER_READ_TIME", "TODO_TASKS_LIMIT", "TODO_JWT_SECRET", "TODO_DBFILE", "TODO_LOG_COMPRESS"} {
+		assert.Contains(t, err.Error(), name)
+	}
+}
+
+func TestLoadMissingExplicitEnvFile(t *testing.T) {
+	_, _, err := config.Load([]string{"-env-file", filepath.Join(t.TempDir(), "absent.env")})
+	assert.ErrorIs(t, err, config.ErrConfigMissing)
+}
+
+func TestLoadValidatesTLS(t *testing.T) {
+	envFile := writeEnvFile(t, "")
+
+	_, _, err := config.Load([]string{
+		"-env-file", envFile,
+		"-tls-cert", "cert.pem",
+		"-tls-min-version", "1.1",
+		"-http-redirect-port", "8080",
+	})
+	require.Error(t, err)
+	for _, name := range []string{"TODO_TLS_KEY", "TODO_TLS_MIN_VERSION"} {
+		assert.Contains(t, err.Error(), name)
+	}
+
+	cfg, _, err := config.Load([]string{"-env-file", envFile, "-tls-dev", "true", "-http-redirect-port", "8080"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Server.TLSEnabled())
+	assert.True(t, cfg.Server.HTTP2)
+	assert.Equal(t, "8080", cfg.Server.RedirectPort)
+}
+
+func TestLoadLogsOnlyChangesOnReload(t *testing.T) {
+	envFile := writeEnvFile(t, "")
+	first, _, err := config.Load([]string{"-env-file", envFile, "-port", "7301"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	defer slog.SetDefault(prev)
+
+	t.Setenv("TODO_PASSWORD", "секрет")
+	t.Setenv("TODO_JWT_SECRET", "подпись")
+	next, _, err := config.Load([]string{"-env-file", envFile, "-port", "7302"})
+	require.NoError(t, err)
+	assert.Empty(t, buf.String(), "значения всех настроек пишутся только на уровне Debug")
+
+	// Загрузка другой конфигурации в том же процессе не влияет на то, с чем сравнивается next
+	_, _, err = config.Load([]string{"-env-file", envFile, "-port", "7399"})
+	require.NoError(t, err)
+
+	next.LogChanges(first)
+	out := buf.String()
+	assert.Contains(t, out, "name=TODO_PORT_7540 old=7301 new=7302")
+	assert.Contains(t, out, "name=TODO_PASSWORD old=\"\" new=***")
+	assert.NotContains(t, out, "секрет")
+	assert.Equal(t, 3, bytes.Count(buf.Bytes(), []byte("Настройка изменена")))
+
+	buf.Reset()
+	next.LogChanges(next)
+	assert.Contains(t, buf.String(), "изменений нет")
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+
+	buf.Reset()
+	first.LogSummary()
+	assert.Contains(t, buf.String(), "Настройки загружены")
+	assert.Contains(t, buf.String(), "flags=1")
+}
@@ -0,0 +1,415 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Имена файлов резервных копий: scheduler-20240201-150405.db. Время в имени позволяет
+// сортировать копии по имени и не зависеть от времени изменения файлов.
+const (
+	backupPrefix     = "scheduler-"
+	backupExt        = ".db"
+	backupTimeFormat = "20060102-150405"
+)
+
+// restoreSuffix - суффикс, с которым сохраняется текущая база данных перед восстановлением из копии.
+const restoreSuffix = ".before-restore"
+
+// lockSuffix - суффикс файла, блокировку которого держит работающий сервер.
+const lockSuffix = ".lock"
+
+var (
+	// ErrBackupInvalid - резервная копия повреждена или не является базой данных планировщика.
+	ErrBackupInvalid = errors.New("резервная копия повреждена")
+	// ErrDatabaseInUse - базой данных пользуется другой процесс, например работающий сервер.
+	ErrDatabaseInUse = errors.New("база данных используется другим процессом")
+
+	errLocked = errors.New("файл заблокирован")
+)
+
+// BackupInfo - файл резервной копии.
+type BackupInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"-"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// Backups создает резервные копии работающей базы данных в директории и хранит только последние из них.
+type Backups struct {
+	db   *sql.DB
+	dir  string
+	keep int
+	mu   sync.Mutex // Копии по расписанию и по запросу не создаются одновременно
+}
+
+// NewBackups возвращает менеджер резервных копий базы db в директории dir, который хранит keep последних копий.
+func NewBackups(db *sql.DB, dir string, keep int) *Backups {
+	return &Backups{db: db, dir: dir, keep: keep}
+}
+
+// Dir возвращает директорию резервных копий.
+func (b *Backups) Dir() string {
+	return b.dir
+}
+
+// Create создает резервную копию и удаляет копии сверх лимита.
+// Копия снимается командой VACUUM INTO, которая не останавливает запись в базу,
+// и попадает в директорию только после успешной проверки целостности.
+func (b *Backups) Create(ctx context.Context) (BackupInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0750); err != nil {
+		return BackupInfo{}, fmt.Errorf("метод Create: не удалось создать директорию %s: %w", b.dir, fsError(err))
+	}
+
+	created := time.Now()
+	path, err := b.nextPath(created)
+	if err != nil {
+		return BackupInfo{}, fmt.Errorf("метод Create: %w", err)
+	}
+
+	// VACUUM INTO не перезаписывает существующий файл, а временный файл мог остаться от прерванной копии
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return BackupInfo{}, fmt.Errorf("метод Create: %w", fsError(err))
+	}
+	if _, err := b.db.ExecContext(ctx, `VACUUM INTO ?`, tmp); err != nil {
+		os.Remove(tmp)
+		return BackupInfo{}, fmt.Errorf("метод Create: ошибка копирования базы данных: %w", err)
+	}
+	if err := VerifyBackup(tmp); err != nil {
+		os.Remove(tmp)
+		return BackupInfo{}, fmt.Errorf("метод Create: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return BackupInfo{}, fmt.Errorf("метод Create: %w", fsError(err))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return BackupInfo{}, fmt.Errorf("метод Create: %w", fsError(err))
+	}
+	backup := BackupInfo{Name: filepath.Base(path), Path: path, Size: info.Size(), Created: created}
+	slog.Info("Создана резервная копия базы данных", "file", path, "size", backup.Size)
+
+	// Копия уже создана, поэтому ошибка удаления старых копий только записывается в лог
+	if removed, err := PruneBackups(b.dir, b.keep); err != nil {
+		slog.Warn("Не удалось удалить старые резервные копии", "dir", b.dir, "error", err)
+	} else if removed > 0 {
+		slog.Info("Удалены старые резервные копии", "count", removed, "keep", b.keep)
+	}
+	return backup, nil
+}
+
+// nextPath возвращает путь для копии, созданной в момент created. Копии, созданные в ту же секунду,
+// получают номер больше, чем у всех уже существующих, иначе новая копия могла бы занять имя удаленной
+// и оказаться самой старой при сортировке.
+func (b *Backups) nextPath(created time.Time) (string, error) {
+	backups, err := ListBackups(b.dir)
+	if err != nil {
+		return "", err
+	}
+	stamp := created.Format(backupTimeFormat)
+	n := 0
+	for _, backup := range backups {
+		if backup.Created.Format(backupTimeFormat) == stamp {
+			n = max(n, backupNumber(backup.Name), 1)
+		}
+	}
+	if n == 0 {
+		return filepath.Join(b.dir, backupPrefix+stamp+backupExt), nil
+	}
+	return filepath.Join(b.dir, fmt.Sprintf("%s%s_%d%s", backupPrefix, stamp, n+1, backupExt)), nil
+}
+
+// Run создает резервные копии с интервалом interval до отмены ctx. Ошибки записываются в лог,
+// следующая попытка будет по расписанию.
+func (b *Backups) Run(ctx context.Context, interval time.Duration) {
+	slog.Info("Автоматическое резервное копирование включено", "dir", b.dir, "interval", interval, "keep", b.keep)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.Create(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Ошибка резервного копирования базы данных", "error", err)
+			}
+		}
+	}
+}
+
+// ListBackups возвращает резервные копии из директории dir, от старых к новым.
+// Если директории нет, копий тоже нет.
+func ListBackups(dir string) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("функция ListBackups: %w", fsError(err))
+	}
+
+	var backups []BackupInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupExt) {
+			continue
+		}
+		stamp, _, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupExt), "_")
+		created, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{Name: name, Path: filepath.Join(dir, name), Size: info.Size(), Created: created})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Created.Equal(backups[j].Created) {
+			return backups[i].Created.Before(backups[j].Created)
+		}
+		return backupNumber(backups[i].Name) < backupNumber(backups[j].Name)
+	})
+	return backups, nil
+}
+
+// backupNumber возвращает номер копии среди созданных в одну секунду. У первой из них номера нет.
+func backupNumber(name string) int {
+	n := 0
+	if _, suffix, ok := strings.Cut(strings.TrimSuffix(name, backupExt), "_"); ok {
+		fmt.Sscan(suffix, &n)
+	}
+	return n
+}
+
+// PruneBackups удаляет из директории dir самые старые резервные копии, оставляя keep последних.
+// Возвращает количество удаленных копий.
+func PruneBackups(dir string, keep int) (int, error) {
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for len(backups)-removed > keep {
+		if err := os.Remove(backups[removed].Path); err != nil {
+			return removed, fmt.Errorf("функция PruneBackups: %w", fsError(err))
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// VerifyBackup открывает файл копии только для чтения и проверяет его через PRAGMA integrity_check,
+// а также что в нем есть таблица задач.
+func VerifyBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("функция VerifyBackup: %w", fsError(err))
+	}
+
+	registerDriver()
+	db, err := sql.Open(DriverName, sqliteURI(path, "mode=ro"))
+	if err != nil {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: %w", ErrBackupInvalid, path, err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`PRAGMA integrity_check`)
+	if err != nil {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: %w", ErrBackupInvalid, path, err)
+	}
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return fmt.Errorf("функция VerifyBackup: %w: %s: %w", ErrBackupInvalid, path, err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: %w", ErrBackupInvalid, path, err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: %s", ErrBackupInvalid, path, strings.Join(problems, "; "))
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'scheduler'`).Scan(&tables); err != nil {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: %w", ErrBackupInvalid, path, err)
+	}
+	if tables == 0 {
+		return fmt.Errorf("функция VerifyBackup: %w: %s: нет таблицы задач", ErrBackupInvalid, path)
+	}
+	return nil
+}
+
+// HoldDatabase отмечает, что процесс работает с базой данных dbFile, пока не будет вызвана release.
+// Отметка - разделяемая блокировка файла рядом с базой: ее могут держать несколько серверов сразу
+// (например, при передаче сокета новому процессу), а RestoreBackup при ней отказывается заменять базу.
+// Вызывается до открытия базы, поэтому при необходимости создает директорию для нее.
+func HoldDatabase(dbFile string) (release func() error, err error) {
+	if err := os.MkdirAll(filepath.Dir(dbFile), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("функция HoldDatabase: %w", fsError(err))
+	}
+	f, err := os.OpenFile(dbFile+lockSuffix, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("функция HoldDatabase: %w", fsError(err))
+	}
+	if err := lockFile(f, false); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("функция HoldDatabase: %w: идет восстановление из резервной копии", ErrDatabaseInUse)
+		}
+		return nil, fmt.Errorf("функция HoldDatabase: %w", fsError(err))
+	}
+	return f.Close, nil
+}
+
+// RestoreBackup заменяет файл базы данных dbFile копией из файла backup. Если базой пользуется сервер
+// (см. HoldDatabase) или другое соединение держит в ней транзакцию, возвращается ErrDatabaseInUse:
+// иначе работающий процесс продолжил бы писать в прежний файл, и эти изменения пропали бы.
+// Текущая база данных вместе с журналами сохраняется рядом с суффиксом .before-restore,
+// чтобы восстановление можно было отменить. Возвращает путь к сохраненной базе или пустую строку, если базы не было.
+func RestoreBackup(backup, dbFile string) (string, error) {
+	if err := VerifyBackup(backup); err != nil {
+		return "", err
+	}
+
+	lock, err := os.OpenFile(dbFile+lockSuffix, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return "", fmt.Errorf("функция RestoreBackup: %w", fsError(err))
+	}
+	defer lock.Close()
+	if err := lockFile(lock, true); err != nil {
+		if errors.Is(err, errLocked) {
+			return "", fmt.Errorf("функция RestoreBackup: %w: остановите сервер", ErrDatabaseInUse)
+		}
+		return "", fmt.Errorf("функция RestoreBackup: %w", fsError(err))
+	}
+
+	if fileExists(dbFile) {
+		if err := checkNotInUse(dbFile); err != nil {
+			return "", err
+		}
+	}
+
+	// Сначала копируем во временный файл рядом с базой, чтобы подмена была одним переименованием
+	tmp := dbFile + ".tmp"
+	if err := copyFile(backup, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("функция RestoreBackup: %w", fsError(err))
+	}
+
+	saved := ""
+	if fileExists(dbFile) {
+		saved = dbFile + restoreSuffix
+		// Журналы переименовываются вместе с базой: SQLite ищет их по имени файла базы
+		for _, suffix := range []string{"", "-wal", "-shm"} {
+			err := os.Rename(dbFile+suffix, saved+suffix)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				os.Remove(tmp)
+				return "", fmt.Errorf("функция RestoreBackup: не удалось сохранить текущую базу данных: %w", fsError(err))
+			}
+			if errors.Is(err, os.ErrNotExist) {
+				// Журнала у текущей базы нет, а старый журнал сохраненной базы к ней уже не относится
+				os.Remove(saved + suffix)
+			}
+		}
+	}
+
+	if err := os.Rename(tmp, dbFile); err != nil {
+		os.Remove(tmp)
+		return saved, fmt.Errorf("функция RestoreBackup: %w", fsError(err))
+	}
+	slog.Info("База данных восстановлена из резервной копии", "file", dbFile, "backup", backup, "saved", saved)
+	return saved, nil
+}
+
+// checkNotInUse проверяет, что в базе dbFile нет чужих транзакций: начинает исключительную транзакцию
+// без ожидания и сразу откатывает ее. Соединение закрывается до подмены файлов, иначе в Windows
+// файл базы нельзя было бы переименовать. Если транзакцию держит другое соединение, возвращается ErrDatabaseInUse.
+func checkNotInUse(dbFile string) error {
+	registerDriver()
+	db, err := sql.Open(DriverName, sqliteURI(dbFile, "mode=rw&_busy_timeout=0"))
+	if err != nil {
+		return fmt.Errorf("функция checkNotInUse: %w: %w", ErrDBUnavailable, err)
+	}
+	defer db.Close()
+	// Транзакция должна идти в одном соединении
+	db.SetMaxOpenConns(1)
+
+	// Ошибки открытия отделяем от блокировки: после успешного подключения BEGIN EXCLUSIVE
+	// без ожидания не проходит, только если базу держит другое соединение
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("функция checkNotInUse: %w: %w", ErrDBUnavailable, err)
+	}
+	if _, err := db.Exec(`BEGIN EXCLUSIVE`); err != nil {
+		return fmt.Errorf("функция checkNotInUse: %w: %w", ErrDatabaseInUse, err)
+	}
+	if _, err := db.Exec(`ROLLBACK`); err != nil {
+		return fmt.Errorf("функция checkNotInUse: %w: %w", ErrDBUnavailable, err)
+	}
+	return nil
+}
+
+// sqliteURI возвращает URI SQLite для файла path с параметрами query. Путь экранируется по сегментам,
+// иначе символы ?, # и % в имени файла были бы приняты за начало параметров, фрагмент или код символа.
+func sqliteURI(path, query string) string {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "file:" + strings.Join(segments, "/") + "?" + query
+}
+
+// copyFile копирует файл src в dst и сбрасывает его содержимое на диск.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fileExists сообщает, есть ли файл по пути path.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,185 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"3code/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openFileDatabase открывает базу в файле path и применяет миграции.
+func openFileDatabase(t *testing.T, path string) *sql.DB {
+	db, err := database.OpenDatabase(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, database.Migrate(db))
+	return db
+}
+
+func TestBackupCreatePruneRestore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "scheduler.db")
+	backupDir := filepath.Join(dir, "backups")
+
+	db := openFileDatabase(t, dbFile)
+	store := database.NewSQLiteStore(db)
+	_, err := store.Add(ctx, database.Task{Date: "20240201", Title: "Из копии"})
+	require.NoError(t, err)
+
+	backups := database.NewBackups(db, backupDir, 2)
+	first, err := backups.Create(ctx)
+	require.NoError(t, err)
+	require.NoError(t, database.VerifyBackup(first.Path))
+
+	// Изменения после копии в нее не попадают
+	_, err = store.Add(ctx, database.Task{Date: "20240202", Title: "После копии"})
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err = backups.Create(ctx)
+		require.NoError(t, err)
+	}
+	list, err := database.ListBackups(backupDir)
+	require.NoError(t, err)
+	require.Len(t, list, 2, "хранятся только последние копии")
+	assert.NotContains(t, []string{list[0].Name, list[1].Name}, first.Name, "удаляется самая старая копия")
+
+	// Восстанавливаем первую копию, сохранив ее заранее: из директории она уже удалена
+	first, err = backups.Create(ctx)
+	require.NoError(t, err)
+	saved := filepath.Join(dir, "first.db")
+	require.NoError(t, os.Rename(first.Path, saved))
+	_, err = store.Add(ctx, database.Task{Date: "20240203", Title: "Потеряется"})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	previous, err := database.RestoreBackup(saved, dbFile)
+	require.NoError(t, err)
+	assert.Equal(t, dbFile+".before-restore", previous)
+	assert.FileExists(t, previous)
+
+	restored := openFileDatabase(t, dbFile)
+	tasks, err := database.NewSQLiteStore(restored).All(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "Из копии", tasks[0].Title)
+	assert.Equal(t, "После копии", tasks[1].Title)
+}
+
+func TestVerifyBackupRejectsBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	garbage := filepath.Join(dir, "garbage.db")
+	require.NoError(t, os.WriteFile(garbage, []byte("это не база данных"), 0600))
+	assert.ErrorIs(t, database.VerifyBackup(garbage), database.ErrBackupInvalid)
+
+	// Пустая база SQLite цела, но задач в ней нет
+	empty := filepath.Join(dir, "empty.db")
+	db, err := database.OpenDatabase(empty)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE other (id INTEGER)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.ErrorIs(t, database.VerifyBackup(empty), database.ErrBackupInvalid)
+
+	_, err = database.RestoreBackup(garbage, filepath.Join(dir, "scheduler.db"))
+	require.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "scheduler.db"), "поврежденная копия не заменяет базу")
+}
+
+func TestRestoreRefusesDatabaseInUse(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "scheduler.db")
+
+	db := openFileDatabase(t, dbFile)
+	backup, err := database.NewBackups(db, filepath.Join(dir, "backups"), 1).Create(ctx)
+	require.NoError(t, err)
+
+	// Другое соединение держит базу открытой и пишет в нее
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.Exec(`INSERT INTO scheduler (date, title) VALUES ('20240201', 'Не записано')`)
+	require.NoError(t, err)
+	_, err = database.RestoreBackup(backup.Path, dbFile)
+	assert.ErrorIs(t, err, database.ErrDatabaseInUse)
+	assert.NoFileExists(t, dbFile+".before-restore", "база, которой пользуются, не заменяется")
+	require.NoError(t, tx.Rollback())
+
+	// Работающий сервер держит отметку, пока не будет вызвана release
+	release, err := database.HoldDatabase(dbFile)
+	require.NoError(t, err)
+	if runtime.GOOS != "windows" {
+		_, err = database.RestoreBackup(backup.Path, dbFile)
+		assert.ErrorIs(t, err, database.ErrDatabaseInUse)
+	}
+	require.NoError(t, release())
+
+	require.NoError(t, db.Close())
+	_, err = database.RestoreBackup(backup.Path, dbFile)
+	require.NoError(t, err)
+}
+
+func TestHoldDatabaseBeforeOpen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("блокировка файлов поддерживается только в Unix")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	source := openFileDatabase(t, filepath.Join(dir, "source.db"))
+	backup, err := database.NewBackups(source, filepath.Join(dir, "backups"), 1).Create(ctx)
+	require.NoError(t, err)
+
+	// Отметка ставится до того, как появились директория и файл базы
+	dbFile := filepath.Join(dir, "data", "scheduler.db")
+	release, err := database.HoldDatabase(dbFile)
+	require.NoError(t, err)
+	assert.NoFileExists(t, dbFile)
+
+	_, err = database.RestoreBackup(backup.Path, dbFile)
+	assert.ErrorIs(t, err, database.ErrDatabaseInUse)
+	assert.NoFileExists(t, dbFile, "пока отметка стоит, база не подменяется")
+
+	require.NoError(t, release())
+	_, err = database.RestoreBackup(backup.Path, dbFile)
+	require.NoError(t, err)
+	assert.FileExists(t, dbFile)
+}
+
+func TestBackupPathWithURISymbols(t *testing.T) {
+	ctx := context.Background()
+	// Символы, которые в URI SQLite означают параметры, фрагмент и код символа. Проверка копии и восстановление
+	// открывают базы по URI. ? есть только в пути к копиям: в Windows он запрещен в именах файлов,
+	// а OpenDatabase открывает базу не по URI
+	dir := t.TempDir()
+	dbDir := filepath.Join(dir, "база #1 50%")
+	backupDir := filepath.Join(dir, "копии #2 25%")
+	if runtime.GOOS != "windows" {
+		backupDir += "?mode=memory"
+	}
+	require.NoError(t, os.MkdirAll(dbDir, 0755))
+	dbFile := filepath.Join(dbDir, "scheduler.db")
+
+	db := openFileDatabase(t, dbFile)
+	_, err := database.NewSQLiteStore(db).Add(ctx, database.Task{Date: "20240201", Title: "Из копии"})
+	require.NoError(t, err)
+	backup, err := database.NewBackups(db, backupDir, 1).Create(ctx)
+	require.NoError(t, err)
+	require.NoError(t, database.VerifyBackup(backup.Path))
+	require.NoError(t, db.Close())
+
+	_, err = database.RestoreBackup(backup.Path, dbFile)
+	require.NoError(t, err)
+	restored := openFileDatabase(t, dbFile)
+	task, err := database.NewSQLiteStore(restored).Get(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Из копии", task.Title)
+}
@@ -2,43 +2,72 @@ package database
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"3code/config"
+
+	"github.com/mattn/go-sqlite3"
 )
 
+// DriverName - имя драйвера SQLite с дополнительными функциями, через который открывается база данных.
+const DriverName = "sqlite3_todo"
+
+var registerDriverOnce sync.Once
+
+// registerDriver регистрирует драйвер SQLite, в каждом соединении которого доступна функция lower_unicode.
+// Встроенная в SQLite функция LOWER и оператор LIKE не учитывают регистр только для латиницы,
+// а заголовки задач в основном на русском.
+func registerDriver() {
+	registerDriverOnce.Do(func() {
+		sql.Register(DriverName, &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				return conn.RegisterFunc("lower_unicode", strings.ToLower, true)
+			},
+		})
+	})
+}
+
 // openDatabase открывает соединение с базой данных.
 // С помощью sql.Open открывается база данных SQLite.
 // Если база данных не существует, то файл будет создан.
-func OpenDatabase(dbFile string) *sql.DB {
-	db, err := sql.Open("sqlite3", dbFile)
+func OpenDatabase(dbFile string) (*sql.DB, error) {
+	registerDriver()
+
+	db, err := sql.Open(DriverName, dbFile)
 	if err != nil {
-		log.Fatalf("Фатальная ошибка открытия базы данных: %v\n", err)
+		return nil, fmt.Errorf("%w: ошибка открытия базы данных %s: %w", ErrDBUnavailable, dbFile, err)
 	}
-	log.Println("Соединение с базой данных успешно установлено:", dbFile)
-	return db
+
+	// sql.Open не подключается к базе, поэтому проверяем соединение явно
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: ошибка подключения к базе данных %s: %w", ErrDBUnavailable, dbFile, err)
+	}
+
+	slog.Info("Соединение с базой данных успешно установлено", "file", dbFile)
+	return db, nil
 }
 
-// createTable создает таблицу и индекс в базе данных.
-func CreateTable(db *sql.DB) {
-	createTableSQL := `
-    CREATE TABLE scheduler (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        date TEXT NOT NULL,
-        title TEXT NOT NULL,
-        comment TEXT,
-        repeat TEXT CHECK(length(repeat) <= 128)
-    );`
-
-	createIndexSQL := `CREATE INDEX idx_date ON scheduler (date);`
-
-	// Выполняем SQL-запрос для создания таблицы
-	if _, err := db.Exec(createTableSQL); err != nil {
-		log.Fatalf("Фатальная ошибка при создании таблицы: %v", err)
+// SetupDatabase подготавливает файл базы данных, открывает ее и применяет все миграции схемы.
+func SetupDatabase(cfg config.Database) (*sql.DB, error) {
+	dbFile, err := PrepareDatabaseFile(cfg)
+	if err != nil {
+		return nil, err
 	}
-	log.Println("Таблица scheduler успешно создана.")
 
-	// Выполняем SQL-запрос для создания индекса
-	if _, err := db.Exec(createIndexSQL); err != nil {
-		log.Fatalf("Фатальная ошибка при создании индекса: %v", err)
+	db, err := OpenDatabase(dbFile)
+	if err != nil {
+		return nil, err
 	}
-	log.Println("Индекс idx_date успешно создан.")
+
+	if err := Migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%w: %w", ErrMigration, err)
+	}
+	slog.Info("Схема базы данных успешно обновлена")
+
+	return db, nil
 }
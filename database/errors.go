@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Ошибки подготовки базы данных. Возвращаются обернутыми, проверять их нужно через errors.Is.
+var (
+	// ErrDBPermission - нет прав на доступ к файлу или директории базы данных.
+	ErrDBPermission = errors.New("нет прав доступа к базе данных")
+	// ErrDBUnavailable - файл базы данных не удалось проверить, создать или открыть.
+	ErrDBUnavailable = errors.New("база данных недоступна")
+	// ErrMigration - не удалось применить миграции схемы.
+	ErrMigration = errors.New("ошибка миграции схемы базы данных")
+)
+
+// fsError оборачивает ошибку файловой системы в ErrDBPermission или ErrDBUnavailable в зависимости от ее причины.
+func fsError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", ErrDBPermission, err)
+	}
+	return fmt.Errorf("%w: %w", ErrDBUnavailable, err)
+}
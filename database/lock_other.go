@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package database
+
+import "os"
+
+// На остальных платформах блокировка файла не поддерживается. Восстановление все равно откажется
+// заменять базу, в которой идет транзакция, а в Windows открытый файл базы и не получится переименовать.
+func lockFile(*os.File, bool) error {
+	return nil
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"3code/rules"
+)
+
+// MemoryStore - реализация TaskStore в памяти процесса.
+// Используется в тестах, где поднимать SQLite не нужно.
+type MemoryStore struct {
+	mu     sync.Mutex
+	tasks  map[string]Task
+	lastID int64
+}
+
+// Проверяем на этапе компиляции, что MemoryStore реализует TaskStore
+var _ TaskStore = (*MemoryStore)(nil)
+
+// NewMemoryStore создает пустое хранилище задач в памяти.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[string]Task)}
+}
+
+// Add сохраняет задачу под новым идентификатором.
+func (s *MemoryStore) Add(_ context.Context, task Task) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	task.ID = strconv.FormatInt(s.lastID, 10)
+	s.tasks[task.ID] = task
+	return task.ID, nil
+}
+
+// Get возвращает задачу по идентификатору.
+func (s *MemoryStore) Get(_ context.Context, id string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return Task{}, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+// Update заменяет задачу с идентификатором task.ID.
+func (s *MemoryStore) Update(_ context.Context, task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[task.ID]; !ok {
+		return ErrTaskNotFound
+	}
+	s.tasks[task.ID] = task
+	return nil
+}
+
+// Delete удаляет задачу по идентификатору.
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[id]; !ok {
+		return ErrTaskNotFound
+	}
+	delete(s.tasks, id)
+	return nil
+}
+
+// List возвращает ближайшие задачи, отсортированные по дате.
+func (s *MemoryStore) List(_ context.Context, limit int) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.filter(limit, func(Task) bool { return true }), nil
+}
+
+// All возвращает все задачи, отсортированные по дате и идентификатору.
+func (s *MemoryStore) All(_ context.Context) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.filter(len(s.tasks), func(Task) bool { return true }), nil
+}
+
+// Each обходит задачи в порядке идентификаторов. Обход идет по снимку, сделанному при вызове.
+func (s *MemoryStore) Each(_ context.Context, fn func(Task) error) error {
+	s.mu.Lock()
+	tasks := make([]Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		tasks = append(tasks, task)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		a, _ := strconv.ParseInt(tasks[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(tasks[j].ID, 10, 64)
+		return a < b
+	})
+	for _, task := range tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import добавляет задачи под одной блокировкой, поэтому другие вызовы видят либо все задачи, либо ни одной.
+func (s *MemoryStore) Import(_ context.Context, tasks []Task, policy ConflictPolicy) ([]ImportResult, error) {
+	// Идентификаторы проверяем до изменений, чтобы ошибка не оставила импорт выполненным наполовину
+	ids := make([]int64, len(tasks))
+	for i, task := range tasks {
+		if task.ID == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(task.ID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("метод Import: некорректный идентификатор задачи %q", task.ID)
+		}
+		ids[i] = id
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]ImportResult, 0, len(tasks))
+	for i, task := range tasks {
+		_, exists := s.tasks[task.ID]
+		result := ImportResult{ID: task.ID, Action: ImportCreated}
+		switch {
+		case exists && policy == ConflictSkip:
+			result.Action = ImportSkipped
+		case exists && policy == ConflictOverwrite:
+			s.tasks[task.ID] = task
+			result.Action = ImportUpdated
+		case task.ID == "" || exists:
+			s.lastID++
+			task.ID = strconv.FormatInt(s.lastID, 10)
+			s.tasks[task.ID] = task
+			result.ID = task.ID
+		default:
+			// Как и AUTOINCREMENT в SQLite, новые идентификаторы продолжаются с наибольшего
+			s.lastID = max(s.lastID, ids[i])
+			s.tasks[task.ID] = task
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Search ищет подстроку query в заголовке и комментарии задач без учета регистра.
+func (s *MemoryStore) Search(_ context.Context, query string, limit int) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = strings.ToLower(query)
+	return s.filter(limit, func(task Task) bool {
+		return strings.Contains(strings.ToLower(task.Title), query) ||
+			strings.Contains(strings.ToLower(task.Comment), query)
+	}), nil
+}
+
+// ListByDate возвращает задачи на указанную дату.
+func (s *MemoryStore) ListByDate(_ context.Context, date string, limit int) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.filter(limit, func(task Task) bool { return task.Date == date }), nil
+}
+
+// CountByRepeat считает задачи по первой букве правила повторения.
+func (s *MemoryStore) CountByRepeat(_ context.Context) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		kind := ""
+		if task.Repeat != "" {
+			kind = task.Repeat[:1]
+		}
+		counts[kind]++
+	}
+	return counts, nil
+}
+
+// MarkDone удаляет разовую задачу или переносит повторяющуюся на следующую дату.
+func (s *MemoryStore) MarkDone(_ context.Context, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if task.Repeat == "" {
+		delete(s.tasks, id)
+		return nil
+	}
+
+	next, err := rules.NextDate(now, task.Date, task.Repeat)
+	if err != nil {
+		return fmt.Errorf("метод MarkDone: ошибка вычисления следующей даты: %w", err)
+	}
+	task.Date = next
+	s.tasks[id] = task
+	return nil
+}
+
+// filter возвращает не более limit подходящих задач в порядке дат, как это делает ORDER BY date.
+// Вызывается под блокировкой.
+func (s *MemoryStore) filter(limit int, match func(Task) bool) []Task {
+	tasks := []Task{}
+	for _, task := range s.tasks {
+		if match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Date != tasks[j].Date {
+			return tasks[i].Date < tasks[j].Date
+		}
+		// При равных датах сохраняем порядок добавления
+		a, _ := strconv.ParseInt(tasks[i].ID, 10, 64)
+		b, _ := strconv.ParseInt(tasks[j].ID, 10, 64)
+		return a < b
+	})
+
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks
+}
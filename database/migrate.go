@@ -0,0 +1,235 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Файлы миграций называются NNNN_описание.up.sql и NNNN_описание.down.sql
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migration - одна версия схемы базы данных.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string // SQL для применения миграции
+	Down    string // SQL для отката миграции
+}
+
+// MigrationStatus - состояние миграции в конкретной базе данных.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// createMigrationsTableSQL создает таблицу, в которой хранятся примененные версии схемы.
+const createMigrationsTableSQL = `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TEXT NOT NULL
+    );`
+
+// Migrations возвращает все встроенные миграции, отсортированные по версии.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationsFS, "migrations")
+}
+
+// loadMigrations читает пары up/down файлов миграций из каталога dir.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога миграций: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("файл миграции %s должен оканчиваться на .up.sql или .down.sql", fileName)
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("файл миграции %s должен начинаться с номера версии", fileName)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла миграции %s: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("у версии %d разные имена миграций: %s и %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("для миграции %04d_%s нужны оба файла: up и down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrate применяет все еще не примененные миграции.
+func Migrate(db *sql.DB) error {
+	_, err := MigrateUp(db, 0)
+	return err
+}
+
+// MigrateUp применяет не более steps непримененных миграций (0 - все) и возвращает количество примененных.
+func MigrateUp(db *sql.DB, steps int) (int, error) {
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, st := range statuses {
+		if st.Applied {
+			continue
+		}
+		if steps > 0 && applied == steps {
+			break
+		}
+
+		if err := applyMigration(db, st.Migration, true); err != nil {
+			return applied, err
+		}
+		applied++
+		slog.Info("Применена миграция", "version", st.Version, "name", st.Name)
+	}
+
+	if applied == 0 {
+		slog.Info("Схема базы данных в актуальном состоянии, миграции не требуются")
+	}
+	return applied, nil
+}
+
+// MigrateDown откатывает не более steps последних примененных миграций и возвращает количество откаченных.
+func MigrateDown(db *sql.DB, steps int) (int, error) {
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	// Откатываем в обратном порядке, начиная с самой новой версии
+	for i := len(statuses) - 1; i >= 0 && reverted < steps; i-- {
+		st := statuses[i]
+		if !st.Applied {
+			continue
+		}
+
+		if err := applyMigration(db, st.Migration, false); err != nil {
+			return reverted, err
+		}
+		reverted++
+		slog.Info("Откачена миграция", "version", st.Version, "name", st.Name)
+	}
+	return reverted, nil
+}
+
+// GetMigrationStatus возвращает список встроенных миграций с отметкой, применены ли они в базе db.
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(createMigrationsTableSQL); err != nil {
+		return nil, fmt.Errorf("ошибка создания таблицы schema_migrations: %w", err)
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("ошибка чтения таблицы schema_migrations: %w", err)
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблицы schema_migrations: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := appliedAt[m.Version]
+		statuses = append(statuses, MigrationStatus{Migration: m, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
+
+// applyMigration применяет (up = true) или откатывает миграцию в одной транзакции вместе с записью в schema_migrations.
+func applyMigration(db *sql.DB, m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("миграция %04d_%s: ошибка начала транзакции: %w", m.Version, m.Name, err)
+	}
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return fmt.Errorf("миграция %04d_%s: ошибка применения: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("миграция %04d_%s: ошибка записи версии: %w", m.Version, m.Name, err)
+		}
+	} else {
+		if _, err := tx.Exec(m.Down); err != nil {
+			return fmt.Errorf("миграция %04d_%s: ошибка отката: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version); err != nil {
+			return fmt.Errorf("миграция %04d_%s: ошибка удаления версии: %w", m.Version, m.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("миграция %04d_%s: ошибка фиксации транзакции: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
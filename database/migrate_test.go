@@ -0,0 +1,65 @@
+package database_test
+
+import (
+	"testing"
+
+	"3code/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateUpDown(t *testing.T) {
+	db, err := database.OpenDatabase(":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations, err := database.Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	require.NoError(t, database.Migrate(db))
+	// Повторный запуск ничего не применяет
+	applied, err := database.MigrateUp(db, 0)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+
+	statuses, err := database.GetMigrationStatus(db)
+	require.NoError(t, err)
+	for _, st := range statuses {
+		assert.True(t, st.Applied, "миграция %d должна быть применена", st.Version)
+	}
+
+	reverted, err := database.MigrateDown(db, len(migrations))
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), reverted)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'scheduler'`).Scan(&count))
+	assert.Zero(t, count)
+}
+
+// TestMigrateExistingDatabase проверяет, что база, созданная до появления миграций, принимается без ошибок.
+func TestMigrateExistingDatabase(t *testing.T) {
+	db, err := database.OpenDatabase(":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE scheduler (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        date TEXT NOT NULL,
+        title TEXT NOT NULL,
+        comment TEXT,
+        repeat TEXT CHECK(length(repeat) <= 128)
+    ); CREATE INDEX idx_date ON scheduler (date);
+    INSERT INTO scheduler (date, title) VALUES ('20240101', 'старая задача');`)
+	require.NoError(t, err)
+
+	require.NoError(t, database.Migrate(db))
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM scheduler`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
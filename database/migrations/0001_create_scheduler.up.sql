@@ -0,0 +1,11 @@
+-- Таблица задач планировщика и индекс по дате.
+-- IF NOT EXISTS позволяет принять под управление базы, созданные до появления миграций.
+CREATE TABLE IF NOT EXISTS scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date TEXT NOT NULL,
+    title TEXT NOT NULL,
+    comment TEXT,
+    repeat TEXT CHECK(length(repeat) <= 128)
+);
+
+CREATE INDEX IF NOT EXISTS idx_date ON scheduler (date);
@@ -0,0 +1,291 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/rules"
+)
+
+// selectTaskSQL - общая часть запросов чтения задач.
+// NULL в необязательных колонках заменяем пустыми строками, чтобы не возиться с sql.NullString.
+const selectTaskSQL = `SELECT id, date, title, COALESCE(comment, ''), COALESCE(repeat, '') FROM scheduler`
+
+// SQLiteStore - реализация TaskStore поверх таблицы scheduler в SQLite.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// Проверяем на этапе компиляции, что SQLiteStore реализует TaskStore
+var _ TaskStore = (*SQLiteStore)(nil)
+
+// NewSQLiteStore создает хранилище задач поверх открытой базы данных.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// Add добавляет задачу в таблицу scheduler и возвращает ее идентификатор.
+func (s *SQLiteStore) Add(ctx context.Context, task Task) (string, error) {
+	res, err := s.db.ExecContext(ctx, `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`,
+		task.Date, task.Title, task.Comment, task.Repeat)
+	if err != nil {
+		return "", fmt.Errorf("метод Add: ошибка добавления задачи: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("метод Add: ошибка получения идентификатора задачи: %w", err)
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// Get возвращает задачу по идентификатору.
+func (s *SQLiteStore) Get(ctx context.Context, id string) (Task, error) {
+	var task Task
+	row := s.db.QueryRowContext(ctx, selectTaskSQL+` WHERE id = ?`, id)
+	if err := row.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, ErrTaskNotFound
+		}
+		return Task{}, fmt.Errorf("метод Get: ошибка чтения задачи: %w", err)
+	}
+	return task, nil
+}
+
+// Update обновляет все поля задачи с идентификатором task.ID.
+func (s *SQLiteStore) Update(ctx context.Context, task Task) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`,
+		task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+	if err != nil {
+		return fmt.Errorf("метод Update: ошибка обновления задачи: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// Delete удаляет задачу по идентификатору.
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM scheduler WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("метод Delete: ошибка удаления задачи: %w", err)
+	}
+	return checkAffected(res)
+}
+
+// List возвращает ближайшие задачи, отсортированные по дате.
+func (s *SQLiteStore) List(ctx context.Context, limit int) ([]Task, error) {
+	return s.query(ctx, selectTaskSQL+` ORDER BY date LIMIT ?`, limit)
+}
+
+// All возвращает все задачи, отсортированные по дате и идентификатору.
+func (s *SQLiteStore) All(ctx context.Context) ([]Task, error) {
+	return s.query(ctx, selectTaskSQL+` ORDER BY date, id`)
+}
+
+// Each обходит задачи в порядке идентификаторов, читая строки из базы по мере обхода.
+func (s *SQLiteStore) Each(ctx context.Context, fn func(Task) error) error {
+	rows, err := s.db.QueryContext(ctx, selectTaskSQL+` ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("метод Each: ошибка получения списка задач: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+			return fmt.Errorf("метод Each: ошибка чтения задачи: %w", err)
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("метод Each: ошибка чтения списка задач: %w", err)
+	}
+	return nil
+}
+
+// Import добавляет задачи одной транзакцией. Явно заданный идентификатор сохраняется,
+// а AUTOINCREMENT после этого продолжает нумерацию с наибольшего из них.
+func (s *SQLiteStore) Import(ctx context.Context, tasks []Task, policy ConflictPolicy) ([]ImportResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("метод Import: ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := func(task Task) (string, error) {
+		var res sql.Result
+		var err error
+		if task.ID == "" {
+			res, err = tx.ExecContext(ctx, `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`,
+				task.Date, task.Title, task.Comment, task.Repeat)
+		} else {
+			res, err = tx.ExecContext(ctx, `INSERT INTO scheduler (id, date, title, comment, repeat) VALUES (?, ?, ?, ?, ?)`,
+				task.ID, task.Date, task.Title, task.Comment, task.Repeat)
+		}
+		if err != nil {
+			return "", err
+		}
+		id, err := res.LastInsertId()
+		return strconv.FormatInt(id, 10), err
+	}
+
+	results := make([]ImportResult, 0, len(tasks))
+	for _, task := range tasks {
+		exists := false
+		if task.ID != "" {
+			err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM scheduler WHERE id = ?)`, task.ID).Scan(&exists)
+			if err != nil {
+				return nil, fmt.Errorf("метод Import: ошибка проверки задачи %s: %w", task.ID, err)
+			}
+		}
+
+		result := ImportResult{ID: task.ID, Action: ImportCreated}
+		switch {
+		case exists && policy == ConflictSkip:
+			result.Action = ImportSkipped
+		case exists && policy == ConflictOverwrite:
+			if _, err := tx.ExecContext(ctx, `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`,
+				task.Date, task.Title, task.Comment, task.Repeat, task.ID); err != nil {
+				return nil, fmt.Errorf("метод Import: ошибка обновления задачи %s: %w", task.ID, err)
+			}
+			result.Action = ImportUpdated
+		case exists && policy == ConflictNewID:
+			task.ID = ""
+			fallthrough
+		default:
+			if result.ID, err = insert(task); err != nil {
+				return nil, fmt.Errorf("метод Import: ошибка добавления задачи: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("метод Import: ошибка фиксации транзакции: %w", err)
+	}
+	return results, nil
+}
+
+// Search ищет подстроку query в заголовке и комментарии задач без учета регистра.
+// Обе стороны сравнения приводятся к нижнему регистру функцией lower_unicode из registerDriver.
+func (s *SQLiteStore) Search(ctx context.Context, query string, limit int) ([]Task, error) {
+	pattern := "%" + escapeLike(strings.ToLower(query)) + "%"
+	return s.query(ctx, selectTaskSQL+`
+        WHERE lower_unicode(title) LIKE ? ESCAPE '\'
+           OR lower_unicode(COALESCE(comment, '')) LIKE ? ESCAPE '\'
+        ORDER BY date LIMIT ?`,
+		pattern, pattern, limit)
+}
+
+// ListByDate возвращает задачи на указанную дату, используя индекс idx_date.
+func (s *SQLiteStore) ListByDate(ctx context.Context, date string, limit int) ([]Task, error) {
+	return s.query(ctx, selectTaskSQL+` WHERE date = ? ORDER BY id LIMIT ?`, date, limit)
+}
+
+// CountByRepeat считает задачи по типу правила повторения одним запросом.
+func (s *SQLiteStore) CountByRepeat(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT substr(COALESCE(repeat, ''), 1, 1) AS kind, count(*) FROM scheduler GROUP BY kind`)
+	if err != nil {
+		return nil, fmt.Errorf("метод CountByRepeat: ошибка подсчета задач: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var kind string
+		var n int
+		if err := rows.Scan(&kind, &n); err != nil {
+			return nil, fmt.Errorf("метод CountByRepeat: ошибка чтения количества задач: %w", err)
+		}
+		counts[kind] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("метод CountByRepeat: ошибка чтения количества задач: %w", err)
+	}
+	return counts, nil
+}
+
+// MarkDone отмечает задачу выполненной в одной транзакции.
+// Разовая задача удаляется, а у повторяющейся дата переносится на следующую по правилу repeat.
+func (s *SQLiteStore) MarkDone(ctx context.Context, id string, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("метод MarkDone: ошибка начала транзакции: %w", err)
+	}
+	// Откат после успешного Commit ничего не делает, поэтому его можно отложить безусловно
+	defer tx.Rollback()
+
+	var date, repeat string
+	row := tx.QueryRowContext(ctx, `SELECT date, COALESCE(repeat, '') FROM scheduler WHERE id = ?`, id)
+	if err := row.Scan(&date, &repeat); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTaskNotFound
+		}
+		return fmt.Errorf("метод MarkDone: ошибка чтения задачи: %w", err)
+	}
+
+	if repeat == "" {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scheduler WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("метод MarkDone: ошибка удаления задачи: %w", err)
+		}
+	} else {
+		next, err := rules.NextDate(now, date, repeat)
+		if err != nil {
+			return fmt.Errorf("метод MarkDone: ошибка вычисления следующей даты: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE scheduler SET date = ? WHERE id = ?`, next, id); err != nil {
+			return fmt.Errorf("метод MarkDone: ошибка переноса даты задачи: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("метод MarkDone: ошибка фиксации транзакции: %w", err)
+	}
+	return nil
+}
+
+// query выполняет запрос, возвращающий список задач.
+func (s *SQLiteStore) query(ctx context.Context, query string, args ...any) ([]Task, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка задач: %w", err)
+	}
+	defer rows.Close()
+
+	// Пустой, но не nil срез, чтобы в JSON попал [], а не null
+	tasks := []Task{}
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(&task.ID, &task.Date, &task.Title, &task.Comment, &task.Repeat); err != nil {
+			return nil, fmt.Errorf("ошибка чтения задачи: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения списка задач: %w", err)
+	}
+	return tasks, nil
+}
+
+// checkAffected возвращает ErrTaskNotFound, если запрос не затронул ни одной строки.
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества измененных строк: %w", err)
+	}
+	if affected == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE, чтобы искать их как обычные символы.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TasksLimit - максимальное количество задач, возвращаемых списком.
+const TasksLimit = 50
+
+// ErrTaskNotFound возвращается, если задача с указанным идентификатором отсутствует в таблице scheduler.
+var ErrTaskNotFound = errors.New("задача не найдена")
+
+// Task - строка таблицы scheduler.
+// Идентификатор передается строкой, так как именно в таком виде его ожидает фронтенд.
+type Task struct {
+	ID      string `json:"id"`
+	Date    string `json:"date"`
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+	Repeat  string `json:"repeat"`
+}
+
+// ConflictPolicy определяет, что делать при импорте задачи с идентификатором, который уже занят.
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"      // Оставить существующую задачу
+	ConflictOverwrite ConflictPolicy = "overwrite" // Заменить существующую задачу импортируемой
+	ConflictNewID     ConflictPolicy = "new-id"    // Добавить импортируемую задачу под новым идентификатором
+)
+
+// ImportAction - что произошло с задачей при импорте.
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+	ImportSkipped ImportAction = "skipped"
+)
+
+// ImportResult - итог импорта одной задачи.
+type ImportResult struct {
+	ID     string       `json:"id"`
+	Action ImportAction `json:"action"`
+}
+
+// TaskStore - хранилище задач планировщика.
+// HTTP-слой работает только через этот интерфейс и не знает, где на самом деле лежат задачи.
+type TaskStore interface {
+	// Add добавляет задачу и возвращает ее идентификатор.
+	Add(ctx context.Context, task Task) (string, error)
+	// Get возвращает задачу по идентификатору или ErrTaskNotFound.
+	Get(ctx context.Context, id string) (Task, error)
+	// Update обновляет все поля задачи с идентификатором task.ID или возвращает ErrTaskNotFound.
+	Update(ctx context.Context, task Task) error
+	// Delete удаляет задачу по идентификатору или возвращает ErrTaskNotFound.
+	Delete(ctx context.Context, id string) error
+	// List возвращает не более limit ближайших задач, отсортированных по дате.
+	List(ctx context.Context, limit int) ([]Task, error)
+	// All возвращает все задачи, отсортированные по дате и идентификатору. Нужна для выгрузки.
+	All(ctx context.Context) ([]Task, error)
+	// Each передает fn задачи по одной в порядке идентификаторов, не загружая все сразу.
+	// Ошибка fn прерывает обход и возвращается как есть.
+	Each(ctx context.Context, fn func(Task) error) error
+	// Import добавляет задачи одной транзакцией: либо все, либо ни одной. Задача с пустым ID получает новый
+	// идентификатор, со свободным ID - добавляется под ним, а с занятым - обрабатывается по policy.
+	// Задачи должны быть заранее проверены, а ID - быть положительным числом.
+	Import(ctx context.Context, tasks []Task, policy ConflictPolicy) ([]ImportResult, error)
+	// Search возвращает не более limit задач, в заголовке или комментарии которых встречается query без учета регистра.
+	Search(ctx context.Context, query string, limit int) ([]Task, error)
+	// ListByDate возвращает не более limit задач на дату date в формате 20060102.
+	ListByDate(ctx context.Context, date string, limit int) ([]Task, error)
+	// CountByRepeat возвращает количество задач по типу правила повторения: первой букве repeat
+	// (d, y, w, m) или пустой строке для разовых задач.
+	CountByRepeat(ctx context.Context) (map[string]int, error)
+	// MarkDone удаляет разовую задачу или переносит повторяющуюся на следующую дату после now.
+	MarkDone(ctx context.Context, id string, now time.Time) error
+}
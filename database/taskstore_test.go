@@ -0,0 +1,166 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"3code/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteStore открывает базу SQLite в памяти с таблицей scheduler.
+func newSQLiteStore(t *testing.T) database.TaskStore {
+	db, err := database.OpenDatabase(":memory:")
+	require.NoError(t, err)
+	// Каждое соединение к :memory: - отдельная база, поэтому ограничиваемся одним
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, database.Migrate(db))
+	return database.NewSQLiteStore(db)
+}
+
+// TestTaskStore проверяет, что обе реализации хранилища ведут себя одинаково.
+func TestTaskStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) database.TaskStore{
+		"memory": func(*testing.T) database.TaskStore { return database.NewMemoryStore() },
+		"sqlite": newSQLiteStore,
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t)
+			now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+			id, err := store.Add(ctx, database.Task{Date: "20240201", Title: "Купить хлеб", Comment: "в булочной"})
+			require.NoError(t, err)
+			repeatID, err := store.Add(ctx, database.Task{Date: "20240127", Title: "Полить цветы", Repeat: "d 3"})
+			require.NoError(t, err)
+
+			task, err := store.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, "Купить хлеб", task.Title)
+
+			counts, err := store.CountByRepeat(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]int{"": 1, "d": 1}, counts)
+
+			tasks, err := store.List(ctx, database.TasksLimit)
+			require.NoError(t, err)
+			require.Len(t, tasks, 2)
+			assert.Equal(t, repeatID, tasks[0].ID, "задачи должны быть отсортированы по дате")
+
+			tasks, err = store.All(ctx)
+			require.NoError(t, err)
+			require.Len(t, tasks, 2)
+			assert.Equal(t, []string{repeatID, id}, []string{tasks[0].ID, tasks[1].ID})
+
+			var ids []string
+			require.NoError(t, store.Each(ctx, func(task database.Task) error {
+				ids = append(ids, task.ID)
+				return nil
+			}))
+			assert.Equal(t, []string{id, repeatID}, ids, "Each идет в порядке идентификаторов")
+
+			tasks, err = store.Search(ctx, "булоч", database.TasksLimit)
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, id, tasks[0].ID)
+
+			// Регистр не учитывается и для кириллицы, а спецсимволы LIKE ищутся как есть
+			tasks, err = store.Search(ctx, "ПОЛИТЬ", database.TasksLimit)
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, repeatID, tasks[0].ID)
+
+			tasks, err = store.Search(ctx, "%", database.TasksLimit)
+			require.NoError(t, err)
+			assert.Empty(t, tasks)
+
+			tasks, err = store.ListByDate(ctx, "20240201", database.TasksLimit)
+			require.NoError(t, err)
+			require.Len(t, tasks, 1)
+			assert.Equal(t, id, tasks[0].ID)
+
+			task.Title = "Купить батон"
+			require.NoError(t, store.Update(ctx, task))
+			task, err = store.Get(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, "Купить батон", task.Title)
+
+			require.NoError(t, store.MarkDone(ctx, repeatID, now))
+			task, err = store.Get(ctx, repeatID)
+			require.NoError(t, err)
+			assert.Equal(t, "20240130", task.Date)
+
+			require.NoError(t, store.MarkDone(ctx, id, now))
+			_, err = store.Get(ctx, id)
+			assert.ErrorIs(t, err, database.ErrTaskNotFound)
+
+			require.NoError(t, store.Delete(ctx, repeatID))
+			assert.ErrorIs(t, store.Delete(ctx, repeatID), database.ErrTaskNotFound)
+			assert.ErrorIs(t, store.Update(ctx, database.Task{ID: repeatID, Title: "x"}), database.ErrTaskNotFound)
+			assert.ErrorIs(t, store.MarkDone(ctx, repeatID, now), database.ErrTaskNotFound)
+		})
+	}
+}
+
+func TestTaskStoreImport(t *testing.T) {
+	stores := map[string]func(t *testing.T) database.TaskStore{
+		"memory": func(*testing.T) database.TaskStore { return database.NewMemoryStore() },
+		"sqlite": newSQLiteStore,
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			policies := map[database.ConflictPolicy][]database.ImportResult{
+				database.ConflictSkip: {
+					{ID: "1", Action: database.ImportSkipped},
+					{ID: "10", Action: database.ImportCreated},
+					{ID: "11", Action: database.ImportCreated},
+				},
+				database.ConflictOverwrite: {
+					{ID: "1", Action: database.ImportUpdated},
+					{ID: "10", Action: database.ImportCreated},
+					{ID: "11", Action: database.ImportCreated},
+				},
+				database.ConflictNewID: {
+					{ID: "2", Action: database.ImportCreated},
+					{ID: "10", Action: database.ImportCreated},
+					{ID: "11", Action: database.ImportCreated},
+				},
+			}
+			for policy, want := range policies {
+				t.Run(string(policy), func(t *testing.T) {
+					store := newStore(t)
+					_, err := store.Add(ctx, database.Task{Date: "20240201", Title: "Старая"})
+					require.NoError(t, err)
+
+					results, err := store.Import(ctx, []database.Task{
+						{ID: "1", Date: "20240301", Title: "Новая"},
+						{ID: "10", Date: "20240302", Title: "С номером", Repeat: "d 2"},
+						{Date: "20240303", Title: "Без номера"},
+					}, policy)
+					require.NoError(t, err)
+					assert.Equal(t, want, results)
+
+					task, err := store.Get(ctx, "1")
+					require.NoError(t, err)
+					if policy == database.ConflictOverwrite {
+						assert.Equal(t, "Новая", task.Title)
+					} else {
+						assert.Equal(t, "Старая", task.Title)
+					}
+					task, err = store.Get(ctx, "10")
+					require.NoError(t, err)
+					assert.Equal(t, "d 2", task.Repeat)
+				})
+			}
+		})
+	}
+}
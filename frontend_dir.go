@@ -0,0 +1,10 @@
+//go:build !embedweb
+
+package main
+
+import "io/fs"
+
+// embeddedFrontend возвращает nil: без тега embedweb файлы фронтенда читаются из директории TODO_FRONTEND_DIR.
+func embeddedFrontend() fs.FS {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build embedweb
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Файлы фронтенда встраиваются в бинарный файл при сборке с тегом embedweb:
+//
+//	go build -tags embedweb
+//
+// Директория web должна существовать на момент сборки. Сжатые копии файлов (.br, .gz) встраиваются вместе с ними.
+//
+//go:embed all:web
+var webFiles embed.FS
+
+// embeddedFrontend возвращает встроенные файлы фронтенда.
+func embeddedFrontend() fs.FS {
+	sub, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// fs.Sub возвращает ошибку только для некорректного имени директории
+		panic(err)
+	}
+	return sub
+}
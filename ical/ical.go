@@ -0,0 +1,133 @@
+// Package ical переводит задачи планировщика в формат iCalendar (RFC 5545), чтобы их можно было смотреть в календарях.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProdID - идентификатор программы, создавшей календарь.
+const ProdID = "-//3code//Планировщик задач//RU"
+
+// maxLineOctets - максимальная длина строки содержимого без CRLF. Длинные строки переносятся (RFC 5545, 3.1).
+const maxLineOctets = 75
+
+// Форматы дат iCalendar: дата для событий на весь день и время UTC для DTSTAMP
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+)
+
+// Event - событие на весь день (VEVENT).
+type Event struct {
+	UID         string
+	Date        time.Time // День события, время суток не учитывается
+	Summary     string
+	Description string
+	RRule       string // Правило повторения без префикса "RRULE:", пусто для разового события
+}
+
+// Calendar - календарь (VCALENDAR) с событиями.
+type Calendar struct {
+	Name string // Название, которое календарные приложения показывают при подписке
+	// Refresh - как часто приложению стоит обновлять календарь при подписке. 0 - не указывать
+	Refresh time.Duration
+	Stamp   time.Time // Время создания календаря, попадает в DTSTAMP каждого события
+	Events  []Event
+}
+
+// Encode записывает календарь в w в формате iCalendar.
+func (c *Calendar) Encode(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", ProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	if c.Refresh > 0 {
+		e.line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.Refresh))
+		e.line("X-PUBLISHED-TTL", formatDuration(c.Refresh))
+	}
+
+	stamp := c.Stamp.UTC().Format(dateTimeFormat)
+	for _, ev := range c.Events {
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", ev.UID)
+		e.line("DTSTAMP", stamp)
+		e.line("DTSTART;VALUE=DATE", ev.Date.Format(dateFormat))
+		e.line("SUMMARY", escapeText(ev.Summary))
+		if ev.Description != "" {
+			e.line("DESCRIPTION", escapeText(ev.Description))
+		}
+		if ev.RRule != "" {
+			e.line("RRULE", ev.RRule)
+		}
+		// Задача не занимает время в календаре
+		e.line("TRANSP", "TRANSPARENT")
+		e.line("END", "VEVENT")
+	}
+
+	e.line("END", "VCALENDAR")
+	if e.err != nil {
+		return fmt.Errorf("метод Encode: ошибка записи календаря: %w", e.err)
+	}
+	if err := e.w.Flush(); err != nil {
+		return fmt.Errorf("метод Encode: ошибка записи календаря: %w", err)
+	}
+	return nil
+}
+
+// encoder пишет строки содержимого и запоминает первую ошибку записи.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line записывает строку "name:value", перенося ее по maxLineOctets байт без разрыва символов UTF-8.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, e.err = e.w.WriteString(s[:cut] + "\r\n "); e.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Пробел в начале строки продолжения тоже считается
+		limit = maxLineOctets - 1
+	}
+	_, e.err = e.w.WriteString(s + "\r\n")
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11).
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// formatDuration записывает длительность в формате DURATION с точностью до минуты, например PT1H или PT90M.
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("PT%dH", minutes/60)
+	}
+	return fmt.Sprintf("PT%dM", minutes)
+}
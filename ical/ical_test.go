@@ -0,0 +1,111 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/ical"
+	"3code/rules"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromRule(t *testing.T) {
+	tests := []struct {
+		repeat string
+		start  string
+		want   string
+		ok     bool
+	}{
+		{"d 3", "20240126", "FREQ=DAILY;INTERVAL=3", true},
+		{"y", "20231106", "FREQ=YEARLY", true},
+		{"y", "20240229", "", false},
+		{"w 1,3", "20240129", "FREQ=WEEKLY;BYDAY=MO,WE", true},
+		{"w 7", "20240126", "", false}, // 26 января 2024 - пятница
+		{"m 1,-1", "20240131", "FREQ=MONTHLY;BYMONTHDAY=-1,1", true},
+		{"m 31 4,8", "20240831", "FREQ=MONTHLY;BYMONTHDAY=31;BYMONTH=4,8", true},
+		{"m 15", "20240126", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repeat+" "+tt.start, func(t *testing.T) {
+			rule, err := rules.Parse(tt.repeat)
+			require.NoError(t, err)
+			start, err := time.Parse(rules.DateFormat, tt.start)
+			require.NoError(t, err)
+
+			got, ok := ical.FromRule(rule, start)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTaskEventsExpandsUnmappedRules(t *testing.T) {
+	from, until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	// Дата задачи не попадает под правило, поэтому повторения разворачиваются
+	events, err := ical.TaskEvents(database.Task{ID: "7", Date: "20240126", Title: "Отчет", Repeat: "m 15"}, from, until)
+	require.NoError(t, err)
+	var dates []string
+	for _, ev := range events {
+		assert.Empty(t, ev.RRule)
+		dates = append(dates, ev.Date.Format(rules.DateFormat))
+	}
+	assert.Equal(t, []string{"20240126", "20240215"}, dates)
+	assert.Equal(t, "task-7-20240215@3code", events[1].UID)
+
+	events, err = ical.TaskEvents(database.Task{ID: "8", Date: "20240126", Title: "Разовая"}, from, until)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "task-8@3code", events[0].UID)
+
+	_, err = ical.TaskEvents(database.Task{ID: "9", Date: "завтра"}, from, until)
+	assert.Error(t, err)
+
+	// У старой задачи в календарь попадают повторения с from, а не с ее даты
+	from, until = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC)
+	events, err = ical.TaskEvents(database.Task{ID: "10", Date: "20200102", Title: "Давняя", Repeat: "w 1,3"}, from, until)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "20250310", events[0].Date.Format(rules.DateFormat), "повторение в день from не пропускается")
+	assert.Equal(t, "20250528", events[len(events)-1].Date.Format(rules.DateFormat))
+}
+
+func TestCalendarEncode(t *testing.T) {
+	cal := ical.Calendar{
+		Name:    "Задачи",
+		Refresh: time.Hour,
+		Stamp:   time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC),
+		Events: []ical.Event{{
+			UID:         "task-1@3code",
+			Date:        time.Date(2024, 1, 27, 0, 0, 0, 0, time.UTC),
+			Summary:     "Купить хлеб, молоко; сыр",
+			Description: "в булочной\nи в магазине " + strings.Repeat("очень ", 20),
+			RRule:       "FREQ=DAILY;INTERVAL=3",
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, cal.Encode(&buf))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, out, "REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n")
+	assert.Contains(t, out, "DTSTAMP:20240126T120000Z\r\n")
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20240127\r\n")
+	assert.Contains(t, out, `SUMMARY:Купить хлеб\, молоко\; сыр`+"\r\n")
+	assert.Contains(t, out, "RRULE:FREQ=DAILY;INTERVAL=3\r\n")
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+
+	// Длинные строки переносятся без разрыва символов, а после склейки совпадают с исходными
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	assert.Contains(t, unfolded, `DESCRIPTION:в булочной\nи в магазине очень`)
+}
@@ -0,0 +1,382 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/database"
+	"3code/rules"
+)
+
+// ErrFormat возвращается, если файл не похож на календарь iCalendar.
+var ErrFormat = errors.New("некорректный формат iCalendar")
+
+// maxLineSize - максимальная длина строки содержимого после склейки переносов.
+const maxLineSize = 1 << 20
+
+// Item - событие (VEVENT) или задача (VTODO) из календаря.
+type Item struct {
+	Component   string // VEVENT или VTODO
+	Line        int    // Номер строки BEGIN, чтобы запись было легко найти в файле
+	UID         string
+	Summary     string
+	Description string
+	Start       string // Значение DTSTART как есть, у VTODO без DTSTART - значение DUE
+	RRule       string
+}
+
+// Parse читает события и задачи из календаря. Вложенные компоненты (например, напоминания VALARM)
+// и прочие свойства пропускаются.
+func Parse(r io.Reader) ([]Item, error) {
+	var (
+		items   []Item
+		current *Item
+		stack   []string
+		due     string
+	)
+
+	err := readLines(r, func(n int, line string) error {
+		name, value, ok := splitLine(line)
+		if !ok {
+			return fmt.Errorf("%w: строка %d: ожидается ИМЯ:ЗНАЧЕНИЕ", ErrFormat, n)
+		}
+
+		switch name {
+		case "BEGIN":
+			value = strings.ToUpper(value)
+			if len(stack) == 0 && value != "VCALENDAR" {
+				return fmt.Errorf("%w: строка %d: календарь должен начинаться с BEGIN:VCALENDAR", ErrFormat, n)
+			}
+			if len(stack) == 1 && (value == "VEVENT" || value == "VTODO") {
+				current, due = &Item{Component: value, Line: n}, ""
+			}
+			stack = append(stack, value)
+			return nil
+
+		case "END":
+			value = strings.ToUpper(value)
+			if len(stack) == 0 || stack[len(stack)-1] != value {
+				return fmt.Errorf("%w: строка %d: END:%s без соответствующего BEGIN", ErrFormat, n, value)
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 1 && current != nil {
+				if current.Start == "" {
+					current.Start = due
+				}
+				items = append(items, *current)
+				current = nil
+			}
+			return nil
+		}
+
+		// Нужны только свойства самого события, а не вложенных в него компонентов
+		if current == nil || len(stack) != 2 {
+			return nil
+		}
+		switch name {
+		case "UID":
+			current.UID = value
+		case "SUMMARY":
+			current.Summary = unescapeText(value)
+		case "DESCRIPTION":
+			current.Description = unescapeText(value)
+		case "DTSTART":
+			current.Start = value
+		case "DUE":
+			due = value
+		case "RRULE":
+			if current.RRule == "" {
+				current.RRule = value
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: не хватает END:%s", ErrFormat, stack[len(stack)-1])
+	}
+	return items, nil
+}
+
+// readLines читает строки содержимого, склеивая перенесенные (RFC 5545, 3.1), и передает их fn с номером строки.
+func readLines(r io.Reader, fn func(n int, line string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var line strings.Builder
+	start, n := 0, 0
+	flush := func() error {
+		if line.Len() == 0 {
+			return nil
+		}
+		err := fn(start, line.String())
+		line.Reset()
+		return err
+	}
+	for sc.Scan() {
+		n++
+		text := strings.TrimRight(sc.Text(), "\r")
+		if text != "" && (text[0] == ' ' || text[0] == '\t') {
+			if line.Len()+len(text) > maxLineSize {
+				return fmt.Errorf("%w: строка %d: слишком длинная строка", ErrFormat, start)
+			}
+			line.WriteString(text[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		start = n
+		line.WriteString(text)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения календаря: %w", err)
+	}
+	return flush()
+}
+
+// splitLine разбирает строку "ИМЯ;ПАРАМЕТРЫ:ЗНАЧЕНИЕ". Параметры не нужны и отбрасываются,
+// но двоеточие внутри кавычек в них не считается разделителем.
+func splitLine(line string) (name, value string, ok bool) {
+	quoted := false
+	for i, c := range line {
+		switch c {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if quoted {
+				continue
+			}
+			name, _, _ = strings.Cut(line[:i], ";")
+			return strings.ToUpper(name), line[i+1:], name != ""
+		}
+	}
+	return "", "", false
+}
+
+// unescapeText убирает экранирование значения типа TEXT.
+func unescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// ToTask переводит событие календаря в задачу. Дата и правило повторения не подгоняются к текущей дате,
+// это делает тот, кто добавляет задачу. Ошибка описывает, почему событие нельзя перенести.
+func ToTask(item Item) (database.Task, error) {
+	task := database.Task{Title: strings.TrimSpace(item.Summary), Comment: item.Description}
+	if task.Title == "" {
+		return database.Task{}, errors.New("нет заголовка (SUMMARY)")
+	}
+
+	var start time.Time
+	if item.Start != "" {
+		var err error
+		if start, err = parseDate(item.Start); err != nil {
+			return database.Task{}, err
+		}
+		task.Date = start.Format(rules.DateFormat)
+	}
+
+	if item.RRule != "" {
+		if item.Start == "" {
+			return database.Task{}, errors.New("правило повторения без даты начала (DTSTART)")
+		}
+		repeat, err := ToRule(item.RRule, start)
+		if err != nil {
+			return database.Task{}, err
+		}
+		task.Repeat = repeat
+	}
+	return task, nil
+}
+
+// parseDate берет дату из значения DATE или DATE-TIME. Время и часовой пояс не учитываются:
+// задачи планировщика привязаны к дню, а день берется тот, что записан в календаре.
+func parseDate(value string) (time.Time, error) {
+	if len(value) < len(dateFormat) {
+		return time.Time{}, fmt.Errorf("некорректная дата %q", value)
+	}
+	date, err := time.Parse(dateFormat, value[:len(dateFormat)])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("некорректная дата %q", value)
+	}
+	return date, nil
+}
+
+// errLeapDayYearly - ежегодное повторение 29 февраля нельзя перевести в правило y без изменения расписания.
+var errLeapDayYearly = errors.New("ежегодное повторение 29 февраля не поддерживается: " +
+	"правило y в невисокосные годы переносит дату на 1 марта, а RRULE такие годы пропускает")
+
+// ToRule переводит RRULE в правило повторения задачи. Это обратное преобразование к FromRule,
+// дополнительно FREQ=DAILY;BYDAY=... переводится в правило w, а FREQ=YEARLY с BYMONTH и BYMONTHDAY - в правило m.
+// Правила с окончанием (COUNT, UNTIL), интервалом у недельных и месячных повторений,
+// ежегодные повторения 29 февраля и прочие части, которых нет в правилах задач, не переводятся.
+func ToRule(rrule string, start time.Time) (string, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", fmt.Errorf("некорректная часть правила %q", part)
+		}
+		key = strings.ToUpper(key)
+		switch key {
+		case "FREQ", "INTERVAL", "BYDAY", "BYMONTHDAY", "BYMONTH":
+			parts[key] = strings.ToUpper(value)
+		case "WKST":
+			// Начало недели не влияет на правила без интервала
+		case "COUNT", "UNTIL":
+			return "", fmt.Errorf("повторения с окончанием (%s) не поддерживаются", key)
+		default:
+			return "", fmt.Errorf("часть правила %s не поддерживается", key)
+		}
+	}
+
+	interval := 1
+	if v, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("некорректный интервал %q", v)
+		}
+		interval = n
+	}
+	freq := parts["FREQ"]
+	if interval != 1 && freq != "DAILY" {
+		return "", errors.New("интервал поддерживается только для FREQ=DAILY")
+	}
+	if _, ok := parts["BYDAY"]; ok && freq != "DAILY" && freq != "WEEKLY" {
+		return "", errors.New("BYDAY поддерживается только для FREQ=DAILY и FREQ=WEEKLY")
+	}
+
+	var repeat string
+	switch freq {
+	case "DAILY":
+		if parts["BYMONTHDAY"] != "" || parts["BYMONTH"] != "" {
+			return "", errors.New("BYMONTHDAY и BYMONTH не поддерживаются для FREQ=DAILY")
+		}
+		if days, ok := parts["BYDAY"]; ok {
+			if interval != 1 {
+				return "", errors.New("BYDAY с интервалом не поддерживается")
+			}
+			weekdays, err := parseWeekdays(days)
+			if err != nil {
+				return "", err
+			}
+			repeat = "w " + weekdays
+		} else {
+			repeat = fmt.Sprintf("d %d", interval)
+		}
+
+	case "WEEKLY":
+		if parts["BYMONTHDAY"] != "" || parts["BYMONTH"] != "" {
+			return "", errors.New("BYMONTHDAY и BYMONTH не поддерживаются для FREQ=WEEKLY")
+		}
+		days, ok := parts["BYDAY"]
+		if !ok {
+			days = weekdayCodes[isoWeekday(start)]
+		}
+		weekdays, err := parseWeekdays(days)
+		if err != nil {
+			return "", err
+		}
+		repeat = "w " + weekdays
+
+	case "MONTHLY":
+		days := parts["BYMONTHDAY"]
+		if days == "" {
+			days = strconv.Itoa(start.Day())
+		}
+		repeat = "m " + days
+		if months := parts["BYMONTH"]; months != "" {
+			repeat += " " + months
+		}
+
+	case "YEARLY":
+		days, months := parts["BYMONTHDAY"], parts["BYMONTH"]
+		if days == "" && months == "" {
+			if isLeapDay(start) {
+				return "", errLeapDayYearly
+			}
+			repeat = "y"
+			break
+		}
+		if days == "" {
+			days = strconv.Itoa(start.Day())
+		}
+		if months == "" {
+			months = strconv.Itoa(int(start.Month()))
+		}
+		repeat = "m " + days + " " + months
+
+	case "":
+		return "", errors.New("в правиле нет FREQ")
+	default:
+		return "", fmt.Errorf("частота %s не поддерживается", freq)
+	}
+
+	// Разбор проверяет диапазоны и приводит правило к каноническому виду
+	rule, err := rules.Parse(repeat)
+	if err != nil {
+		return "", err
+	}
+	if rule.Kind == rules.Monthly && len(rule.MonthDays) == 1 && len(rule.Months) == 1 &&
+		rule.MonthDays[0] == start.Day() && rule.Months[0] == int(start.Month()) {
+		if isLeapDay(start) {
+			return "", errLeapDayYearly
+		}
+		return string(rules.Yearly), nil
+	}
+	return rule.String(), nil
+}
+
+// isLeapDay сообщает, приходится ли дата на 29 февраля.
+func isLeapDay(t time.Time) bool {
+	return t.Month() == time.February && t.Day() == 29
+}
+
+// parseWeekdays переводит список дней BYDAY в номера дней правила w. Дни с номером (1MO, -1FR) не поддерживаются.
+func parseWeekdays(list string) (string, error) {
+	var days []int
+	for _, code := range strings.Split(list, ",") {
+		n := 0
+		for i, c := range weekdayCodes {
+			if c != "" && c == code {
+				n = i
+			}
+		}
+		if n == 0 {
+			return "", fmt.Errorf("день недели %q не поддерживается", code)
+		}
+		days = append(days, n)
+	}
+	sort.Ints(days)
+	return joinInts(days), nil
+}
+
+// isoWeekday возвращает номер дня недели, где 1 - понедельник, а 7 - воскресенье.
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
@@ -0,0 +1,131 @@
+package ical_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"3code/ical"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Moscow\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:1@example\r\n" +
+	"DTSTART;TZID=\"Europe/Moscow\":20240129T090000\r\n" +
+	"SUMMARY:Планерка\\, команда\r\n" +
+	"DESCRIPTION:первая строка\\nвторая \r\n" +
+	" строка\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:напоминание\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:2@example\r\n" +
+	"DUE;VALUE=DATE:20240201\r\n" +
+	"SUMMARY:Сдать отчет\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	items, err := ical.Parse(strings.NewReader(sampleCalendar))
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, ical.Item{
+		Component:   "VEVENT",
+		Line:        6,
+		UID:         "1@example",
+		Summary:     "Планерка, команда",
+		Description: "первая строка\nвторая строка",
+		Start:       "20240129T090000",
+		RRule:       "FREQ=WEEKLY;BYDAY=MO,WE",
+	}, items[0])
+	assert.Equal(t, "VTODO", items[1].Component)
+	assert.Equal(t, "20240201", items[1].Start, "у задачи без DTSTART берется DUE")
+
+	task, err := ical.ToTask(items[0])
+	require.NoError(t, err)
+	assert.Equal(t, "20240129", task.Date)
+	assert.Equal(t, "w 1,3", task.Repeat)
+
+	for _, broken := range []string{
+		"BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nбез двоеточия\r\nEND:VCALENDAR\r\n",
+	} {
+		_, err := ical.Parse(strings.NewReader(broken))
+		assert.ErrorIs(t, err, ical.ErrFormat, broken)
+	}
+}
+
+func TestToRule(t *testing.T) {
+	start := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC) // пятница
+	tests := []struct {
+		rrule   string
+		want    string
+		wantErr bool
+	}{
+		{"FREQ=DAILY", "d 1", false},
+		{"FREQ=DAILY;INTERVAL=3", "d 3", false},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", "w 1,2,3,4,5", false},
+		{"FREQ=WEEKLY", "w 5", false},
+		{"FREQ=WEEKLY;BYDAY=SU,MO;WKST=MO", "w 1,7", false},
+		{"FREQ=MONTHLY", "m 26", false},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,1;BYMONTH=2", "m -1,1 2", false},
+		{"FREQ=YEARLY", "y", false},
+		{"FREQ=YEARLY;BYMONTH=1;BYMONTHDAY=26", "y", false},
+		{"FREQ=YEARLY;BYMONTH=3,9", "m 26 3,9", false},
+		{"FREQ=DAILY;COUNT=5", "", true},
+		{"FREQ=WEEKLY;INTERVAL=2", "", true},
+		{"FREQ=MONTHLY;BYDAY=1MO", "", true},
+		{"FREQ=WEEKLY;BYDAY=1MO", "", true},
+		{"FREQ=HOURLY", "", true},
+		{"FREQ=DAILY;INTERVAL=1000", "", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-3", "", true},
+		{"INTERVAL=2", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rrule, func(t *testing.T) {
+			got, err := ical.ToRule(tt.rrule, start)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// Правило y переносит 29 февраля на 1 марта, а RRULE пропускает невисокосные годы, поэтому такие правила не переводятся
+	leapDay := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	tests = []struct {
+		rrule   string
+		want    string
+		wantErr bool
+	}{
+		{"FREQ=YEARLY", "", true},
+		{"FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "", true},
+		{"FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=29", "", true},
+		{"FREQ=MONTHLY", "m 29", false},
+	}
+	for _, tt := range tests {
+		t.Run("29 февраля "+tt.rrule, func(t *testing.T) {
+			got, err := ical.ToRule(tt.rrule, leapDay)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
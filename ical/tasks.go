@@ -0,0 +1,131 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/database"
+	"3code/rules"
+)
+
+// Ограничения на развернутые повторения задач, правило которых нельзя записать как RRULE
+const (
+	ExpandHorizon = 365 * 24 * time.Hour // Насколько вперед от текущей даты разворачиваются повторения
+	maxExpanded   = 100                  // Сколько повторений одной задачи попадает в календарь максимум
+)
+
+// weekdayCodes - дни недели RRULE по номерам дней в правилах w (1 - понедельник)
+var weekdayCodes = [...]string{1: "MO", 2: "TU", 3: "WE", 4: "TH", 5: "FR", 6: "SA", 7: "SU"}
+
+// FromRule переводит правило повторения задачи в RRULE:
+//
+//	d N           -> FREQ=DAILY;INTERVAL=N
+//	y             -> FREQ=YEARLY
+//	w 1,3         -> FREQ=WEEKLY;BYDAY=MO,WE
+//	m 1,15 [1,6]  -> FREQ=MONTHLY;BYMONTHDAY=1,15[;BYMONTH=1,6]
+//
+// RRULE начинается с даты задачи, а правила w и m дают даты по календарю, поэтому перевод точен,
+// только если дата задачи сама подходит под правило. Иначе, как и для y с датой 29 февраля,
+// возвращается false: повторения такой задачи нужно развернуть в отдельные события.
+func FromRule(rule *rules.Rule, start time.Time) (string, bool) {
+	switch rule.Kind {
+	case rules.Daily:
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", rule.Interval), true
+
+	case rules.Yearly:
+		// Задача с 29 февраля переносится на 1 марта, а RRULE пропускает невисокосные годы
+		if isLeapDay(start) {
+			return "", false
+		}
+		return "FREQ=YEARLY", true
+
+	case rules.Weekly:
+		if !rule.Matches(start) {
+			return "", false
+		}
+		days := make([]string, len(rule.Weekdays))
+		for i, d := range rule.Weekdays {
+			days[i] = weekdayCodes[d]
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(days, ","), true
+
+	case rules.Monthly:
+		if !rule.Matches(start) {
+			return "", false
+		}
+		rrule := "FREQ=MONTHLY;BYMONTHDAY=" + joinInts(rule.MonthDays)
+		if len(rule.Months) > 0 {
+			rrule += ";BYMONTH=" + joinInts(rule.Months)
+		}
+		return rrule, true
+	}
+	return "", false
+}
+
+// TaskEvents возвращает события календаря для задачи. Повторяющаяся задача дает одно событие с RRULE,
+// а если правило нельзя перевести, то отдельные события на каждое повторение с from до until:
+// прошедшие повторения старой задачи не нужны и не должны вытеснять будущие из лимита maxExpanded.
+// Задача с правилом, которое не удалось разобрать, попадает в календарь один раз, на свою дату.
+func TaskEvents(task database.Task, from, until time.Time) ([]Event, error) {
+	start, err := time.Parse(rules.DateFormat, task.Date)
+	if err != nil {
+		return nil, fmt.Errorf("функция TaskEvents: задача %s: некорректная дата %q: %w", task.ID, task.Date, err)
+	}
+	event := Event{
+		UID:         taskUID(task.ID, ""),
+		Date:        start,
+		Summary:     task.Title,
+		Description: task.Comment,
+	}
+	if task.Repeat == "" {
+		return []Event{event}, nil
+	}
+
+	rule, err := rules.Parse(task.Repeat)
+	if err != nil {
+		return []Event{event}, nil
+	}
+	if rrule, ok := FromRule(rule, start); ok {
+		event.RRule = rrule
+		return []Event{event}, nil
+	}
+
+	// Повторения идут так же, как при отметке о выполнении: каждая следующая дата считается от предыдущей.
+	// Первое повторение не раньше from - то, что выдала бы отметка о выполнении накануне from
+	day := start
+	if first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC); day.Before(first) {
+		if day, err = rule.Next(first.AddDate(0, 0, -1), start); err != nil {
+			return []Event{event}, nil
+		}
+	}
+	var events []Event
+	for !day.After(until) && len(events) < maxExpanded {
+		occurrence := event
+		occurrence.UID = taskUID(task.ID, day.Format(dateFormat))
+		occurrence.Date = day
+		events = append(events, occurrence)
+
+		if day, err = rule.Next(day, day); err != nil {
+			break
+		}
+	}
+	return events, nil
+}
+
+// taskUID возвращает постоянный идентификатор события задачи, чтобы календари обновляли события, а не дублировали их.
+func taskUID(id, date string) string {
+	if date == "" {
+		return fmt.Sprintf("task-%s@3code", id)
+	}
+	return fmt.Sprintf("task-%s-%s@3code", id, date)
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
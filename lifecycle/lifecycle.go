@@ -0,0 +1,117 @@
+// Пакет lifecycle управляет корректной остановкой приложения: по очереди вызывает зарегистрированные
+// шаги остановки, ограничивая время каждого шага, и собирает их ошибки.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrHookTimeout возвращается обернутым, если шаг остановки не уложился в свой таймаут.
+var ErrHookTimeout = errors.New("шаг остановки не завершился вовремя")
+
+// Hook - шаг остановки. Fn должна завершиться, как только отменяется ее контекст.
+type Hook struct {
+	Name    string
+	Timeout time.Duration // 0 - таймаут менеджера по умолчанию
+	Fn      func(ctx context.Context) error
+}
+
+// Manager хранит шаги остановки и выполняет их один раз в порядке добавления.
+type Manager struct {
+	defaultTimeout time.Duration
+
+	mu    sync.Mutex
+	hooks []Hook
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// New создает менеджер. defaultTimeout ограничивает шаги, для которых таймаут не задан.
+func New(defaultTimeout time.Duration) *Manager {
+	return &Manager{defaultTimeout: defaultTimeout, done: make(chan struct{})}
+}
+
+// Add добавляет шаг остановки в конец очереди.
+func (m *Manager) Add(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, Hook{Name: name, Timeout: timeout, Fn: fn})
+}
+
+// Shutdown выполняет все шаги по очереди. Ошибка или таймаут одного шага не отменяют следующие:
+// все ошибки возвращаются вместе. Повторные и параллельные вызовы дожидаются первого и возвращают его результат.
+// Отмена ctx прерывает текущий шаг, а оставшиеся получают уже отмененный контекст.
+//
+// Шаг, не уложившийся в таймаут, не ждут: он продолжает выполняться в фоне одновременно со следующими шагами,
+// иначе один зависший шаг задержал бы остановку без ограничения. Поэтому шаг, которому нельзя пересекаться
+// с предыдущими, должен сам дождаться того, что ему нужно (например, sql.DB.Close ждет начатых запросов).
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.once.Do(func() {
+		defer close(m.done)
+
+		m.mu.Lock()
+		hooks := append([]Hook(nil), m.hooks...)
+		m.mu.Unlock()
+
+		slog.Info("Начата остановка приложения", "steps", len(hooks))
+		start := time.Now()
+
+		var errs []error
+		for _, hook := range hooks {
+			if err := m.run(ctx, hook); err != nil {
+				slog.Error("Ошибка шага остановки", "step", hook.Name, "error", err)
+				errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+			}
+		}
+		m.err = errors.Join(errs...)
+
+		slog.Info("Остановка приложения завершена", "duration", time.Since(start), "errors", len(errs))
+	})
+
+	<-m.done
+	return m.err
+}
+
+// Done закрывается после завершения Shutdown.
+func (m *Manager) Done() <-chan struct{} {
+	return m.done
+}
+
+// run выполняет один шаг с его таймаутом. Если шаг не реагирует на отмену контекста,
+// его не ждем дольше таймаута и переходим к следующему.
+func (m *Manager) run(ctx context.Context, hook Hook) error {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = m.defaultTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	slog.Debug("Шаг остановки начат", "step", hook.Name, "timeout", timeout)
+	start := time.Now()
+
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				result <- fmt.Errorf("паника: %v", p)
+			}
+		}()
+		result <- hook.Fn(hookCtx)
+	}()
+
+	select {
+	case err := <-result:
+		slog.Info("Шаг остановки завершен", "step", hook.Name, "duration", time.Since(start))
+		return err
+	case <-hookCtx.Done():
+		return fmt.Errorf("%w за %s: %w", ErrHookTimeout, timeout, hookCtx.Err())
+	}
+}
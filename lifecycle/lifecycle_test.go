@@ -0,0 +1,98 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"3code/lifecycle"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownRunsHooksInOrder(t *testing.T) {
+	m := lifecycle.New(time.Second)
+
+	var order []string
+	errDB := errors.New("база данных занята")
+	m.Add("http", 0, func(context.Context) error { order = append(order, "http"); return nil })
+	m.Add("logs", 0, func(context.Context) error { order = append(order, "logs"); return nil })
+	m.Add("db", 0, func(context.Context) error { order = append(order, "db"); return errDB })
+
+	err := m.Shutdown(context.Background())
+	assert.ErrorIs(t, err, errDB)
+	assert.Contains(t, err.Error(), "db")
+	assert.Equal(t, []string{"http", "logs", "db"}, order)
+
+	// Повторный вызов не выполняет шаги снова и возвращает тот же результат
+	assert.ErrorIs(t, m.Shutdown(context.Background()), errDB)
+	assert.Len(t, order, 3)
+	select {
+	case <-m.Done():
+	default:
+		t.Fatal("Done должен быть закрыт после Shutdown")
+	}
+}
+
+func TestShutdownHookTimeout(t *testing.T) {
+	m := lifecycle.New(time.Second)
+
+	release := make(chan struct{})
+	defer close(release)
+	m.Add("зависший шаг", 50*time.Millisecond, func(context.Context) error {
+		<-release // Не реагирует на отмену контекста
+		return nil
+	})
+	m.Add("паника", 0, func(context.Context) error { panic("сломалось") })
+
+	var mu sync.Mutex
+	ran := false
+	m.Add("следующий шаг", 0, func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = true
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	err := m.Shutdown(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, lifecycle.ErrHookTimeout)
+	assert.Contains(t, err.Error(), "сломалось")
+	assert.Less(t, time.Since(start), time.Second, "зависший шаг не задерживает остановку дольше своего таймаута")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, ran, "ошибки предыдущих шагов не отменяют следующие")
+}
+
+func TestShutdownDoesNotWaitForTimedOutHook(t *testing.T) {
+	m := lifecycle.New(time.Second)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	m.Add("зависший шаг", 20*time.Millisecond, func(context.Context) error {
+		defer close(finished)
+		<-release
+		return nil
+	})
+	stillRunning := false
+	m.Add("следующий шаг", 0, func(context.Context) error {
+		select {
+		case <-finished:
+		default:
+			stillRunning = true
+		}
+		return nil
+	})
+
+	err := m.Shutdown(context.Background())
+	assert.ErrorIs(t, err, lifecycle.ErrHookTimeout)
+	assert.True(t, stillRunning, "следующий шаг начинается, пока шаг с истекшим таймаутом еще выполняется")
+
+	close(release)
+	<-finished
+}
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// maxPending - сколько записей хранится до инициализации логгера. Более поздние записи отбрасываются,
+// чтобы зависший запуск не съел всю память.
+const maxPending = 10000
+
+// pendingRecord - запись, сделанная до инициализации логгера, вместе с атрибутами и группами логгера, который ее сделал.
+type pendingRecord struct {
+	chain  []func(slog.Handler) slog.Handler
+	record slog.Record
+}
+
+// bootstrapSink накапливает записи до появления настоящего обработчика, а после - передает их ему.
+type bootstrapSink struct {
+	mu      sync.Mutex
+	pending []pendingRecord
+	dropped int
+	target  slog.Handler // nil, пока логгер не инициализирован
+}
+
+// bootstrap - общий приемник для всех логгеров, созданных до InitLogger.
+var bootstrap = &bootstrapSink{}
+
+// Bootstrap делает логгером по умолчанию временный логгер, который запоминает все записи
+// (с исходным временем, уровнем и источником) до вызова InitLogger. InitLogger передает их
+// в настроенный вывод в том порядке, в котором они были сделаны.
+// Вызывать нужно в самом начале работы программы, до любого логирования.
+func Bootstrap() {
+	bootstrap.mu.Lock()
+	bootstrap.pending, bootstrap.dropped, bootstrap.target = nil, 0, nil
+	bootstrap.mu.Unlock()
+
+	slog.SetDefault(slog.New(&bootstrapHandler{sink: bootstrap}))
+}
+
+// FlushPending выводит в stderr записи, которые так и не дошли до настроенного логгера,
+// например если программа завершилась с ошибкой конфигурации. После успешного InitLogger ничего не делает.
+func FlushPending() {
+	bootstrap.flush(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{AddSource: true, ReplaceAttr: shortSource}))
+}
+
+// flush передает накопленные записи обработчику h, и дальнейшие записи временных логгеров тоже идут в него.
+func (s *bootstrapSink) flush(h slog.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.target != nil {
+		return
+	}
+	for _, p := range s.pending {
+		handle(h, p.chain, p.record)
+	}
+	if s.dropped > 0 {
+		r := slog.NewRecord(s.pending[len(s.pending)-1].record.Time, slog.LevelWarn, "Часть логов до запуска логгера отброшена", 0)
+		r.AddAttrs(slog.Int("dropped", s.dropped))
+		handle(h, nil, r)
+	}
+	s.pending, s.dropped, s.target = nil, 0, h
+}
+
+// handle применяет к h атрибуты и группы из chain и передает ему запись, если ее уровень включен.
+func handle(h slog.Handler, chain []func(slog.Handler) slog.Handler, r slog.Record) {
+	for _, apply := range chain {
+		h = apply(h)
+	}
+	if h.Enabled(context.Background(), r.Level) {
+		_ = h.Handle(context.Background(), r)
+	}
+}
+
+// bootstrapHandler - обработчик временного логгера. Уровень еще не известен, поэтому запоминаются все записи,
+// а отбор по уровню делает настоящий обработчик при передаче.
+type bootstrapHandler struct {
+	sink  *bootstrapSink
+	chain []func(slog.Handler) slog.Handler
+}
+
+func (h *bootstrapHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	h.sink.mu.Lock()
+	target := h.sink.target
+	h.sink.mu.Unlock()
+
+	if target != nil {
+		return target.Enabled(ctx, l)
+	}
+	return true
+}
+
+func (h *bootstrapHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+
+	// Логгер уже инициализирован, а запись пришла от логгера, сохраненного раньше
+	if h.sink.target != nil {
+		handle(h.sink.target, h.chain, r)
+		return nil
+	}
+	if len(h.sink.pending) >= maxPending {
+		h.sink.dropped++
+		return nil
+	}
+	h.sink.pending = append(h.sink.pending, pendingRecord{chain: h.chain, record: r.Clone()})
+	return nil
+}
+
+func (h *bootstrapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *bootstrapHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *bootstrapHandler) with(apply func(slog.Handler) slog.Handler) *bootstrapHandler {
+	chain := make([]func(slog.Handler) slog.Handler, len(h.chain), len(h.chain)+1)
+	copy(chain, h.chain)
+	return &bootstrapHandler{sink: h.sink, chain: append(chain, apply)}
+}
@@ -0,0 +1,52 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"3code/config"
+	"3code/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootstrapReplaysRecords(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	logger.Bootstrap()
+	early := slog.With("component", "config").WithGroup("env")
+	early.Info("до настройки", "name", "TODO_PORT_7540")
+	slog.Debug("отладка до настройки")
+	slog.Warn("предупреждение до настройки")
+	written := time.Now()
+
+	time.Sleep(10 * time.Millisecond)
+	var buf bytes.Buffer
+	logger.SetupLogOutput(&buf, config.Logger{Level: slog.LevelInfo, Format: "json"})
+	early.Info("после настройки")
+	logger.FlushPending() // Все уже передано, повторно ничего не выводится
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records = append(records, rec)
+	}
+	require.Len(t, records, 3, "запись уровня DEBUG отбрасывается по уровню из настроек")
+
+	assert.Equal(t, "до настройки", records[0]["msg"])
+	assert.Equal(t, "config", records[0]["component"])
+	assert.Equal(t, map[string]any{"name": "TODO_PORT_7540"}, records[0]["env"])
+	ts, err := time.Parse(time.RFC3339Nano, records[0]["time"].(string))
+	require.NoError(t, err)
+	assert.False(t, ts.After(written), "сохраняется исходное время записи")
+
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, "после настройки", records[2]["msg"], "логгер, созданный до настройки, пишет в новый вывод")
+}
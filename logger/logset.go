@@ -1,80 +1,140 @@
 package logger
 
 import (
-	"bytes"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
+
+	"3code/config"
 )
 
-// Глобальный буфер для временного хранения "невозможных" логов
-var logBuffer bytes.Buffer
+// level - текущий уровень логирования. LevelVar позволяет менять уровень, не пересоздавая логгер.
+var level = new(slog.LevelVar)
+
+// ErrLogSetup возвращается обернутым, если не удалось подготовить директорию или файл логов.
+var ErrLogSetup = errors.New("не удалось настроить логирование")
 
 // initLogger инициализирует логирование, создает директорию для логов и файл для записи логов.
-func InitLogger() {
-	logDir := "./09_logs"
+// Логи пишутся одновременно в stderr и в файл, который остается открытым до вызова Close у возвращенного значения.
+func InitLogger(cfg config.Logger) (*RotatingWriter, error) {
+	logDir := cfg.Dir
 
 	// Проверяем, существует ли директория для логов
-	CreateLogDirectory(logDir)
+	if err := CreateLogDirectory(logDir); err != nil {
+		return nil, err
+	}
 
-	// Создаем файл для логов с текущей датой и временем
-	logFile := CreateLogFile(logDir)
+	// Создаем файл для логов с текущей датой и временем, дальше он ротируется сам
+	logFile, err := CreateLogFile(logDir, cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// Перенаправляем вывод логов в файл и устанавливаем формат логов
-	SetupLogOutput(logFile)
+	// Перенаправляем вывод логов в файл и в консоль и устанавливаем формат логов.
+	// Записи, сделанные до этого момента, попадают туда же
+	SetupLogOutput(Tee(logFile, os.Stderr), cfg)
 
-	// Сохраняем логи из буфера в файл
-	SaveLogsToFile(logFile)
+	slog.Info("Логгер успешно запущен", "file", logFile.Name(), "level", level.Level().String(), "format", cfg.Format)
+	return logFile, nil
+}
 
-	log.Println("Логгер успешно запущен")
+// Tee возвращает io.Writer, который пишет в primary и копирует записанное в mirrors.
+// В отличие от io.MultiWriter ошибки mirrors не учитываются: если stderr закрыт (например, у службы),
+// логи все равно должны попадать в файл.
+func Tee(primary io.Writer, mirrors ...io.Writer) io.Writer {
+	return &teeWriter{primary: primary, mirrors: mirrors}
+}
+
+type teeWriter struct {
+	primary io.Writer
+	mirrors []io.Writer
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.primary.Write(p)
+	for _, w := range t.mirrors {
+		w.Write(p)
+	}
+	return n, err
 }
 
 // createLogDirectory проверяет существование директории для логов и создает ее при необходимости.
-func CreateLogDirectory(logDir string) {
+func CreateLogDirectory(logDir string) error {
 	if _, err := os.Stat(logDir); os.IsNotExist(err) {
 		// Директория не существует, создаем ее
 		if err := os.MkdirAll(logDir, os.ModePerm); err != nil {
-			log.Fatalf("Не удалось создать директорию для логов: %v", err)
-		} else {
-			// Записываем в буфер, потому что файл логов еще не создан
-			logBuffer.WriteString(time.Now().Format("2006-01-02 15:04:05") + " Директория для логов создана успешно.\n")
+			return fmt.Errorf("%w: не удалось создать директорию для логов: %w", ErrLogSetup, err)
 		}
+		slog.Info("Директория для логов создана успешно", "dir", logDir)
 	} else {
 		// Директория уже существует
-		logBuffer.WriteString(time.Now().Format("2006-01-02 15:04:05") + " Директория для логов уже существует.\n")
+		slog.Debug("Директория для логов уже существует", "dir", logDir)
 	}
+	return nil
 }
 
-// createLogFile создает файл для логов с текущей датой и временем.
-func CreateLogFile(logDir string) *os.File {
-	logFileName := filepath.Join(logDir, time.Now().Format("2006-01-02_15-04-05")+".log")
-	// os.O_CREATE: флаг указывает, что если файл не существует, он должен быть создан.
-	// os.O_WRONLY: флаг указывает, что файл будет открыт только для записи. Это означает, что не получится читать данные из файла, только писать в него.
-	// os.O_APPEND: флаг указывает, что данные, которые записываются в файл, должны добавляться в конец файла, а не перезаписывать его содержимое.
-	logFile, err := os.OpenFile(logFileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+// createLogFile создает файл для логов с текущей датой и временем и настраивает его ротацию по правилам из cfg.
+func CreateLogFile(logDir string, cfg config.Logger) (*RotatingWriter, error) {
+	logFile, err := NewRotatingWriter(logDir, RotateOptions{
+		MaxSize:  cfg.MaxSizeMB << 20,
+		MaxFiles: cfg.MaxFiles,
+		MaxAge:   time.Duration(cfg.MaxAgeDays) * 24 * time.Hour,
+		Compress: cfg.Compress,
+	})
 	if err != nil {
-		log.Fatalf("Не удалось открыть или создать файл логов: %v", err)
-	} else {
-		logBuffer.WriteString(time.Now().Format("2006-01-02 15:04:05") + " Файл логов успешно создан.\n")
+		return nil, err
 	}
-	return logFile
+	slog.Info("Файл логов успешно создан", "file", logFile.Name())
+	return logFile, nil
+}
+
+// setupLogOutput делает логгер, пишущий в w, логгером по умолчанию и передает ему записи, накопленные после Bootstrap.
+// Сообщения стандартного пакета log после этого тоже проходят через него с уровнем INFO.
+func SetupLogOutput(w io.Writer, cfg config.Logger) {
+	l := New(w, cfg)
+	bootstrap.flush(l.Handler())
+	slog.SetDefault(l)
+}
+
+// New создает логгер с форматом и уровнем из cfg, пишущий в w.
+// Формат text: time=2024-10-31T12:34:56.000+03:00 level=INFO source=main.go:10 msg="Это логовое сообщение" key=value
+func New(w io.Writer, cfg config.Logger) *slog.Logger {
+	level.Set(cfg.Level)
+
+	opts := &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       level,
+		ReplaceAttr: shortSource,
+	}
+
+	if cfg.Format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// SetLevel меняет уровень логирования уже созданных логгеров.
+func SetLevel(l slog.Level) {
+	level.Set(l)
 }
 
-// setupLogOutput перенаправляет вывод логов в файл и устанавливает параметры логирования.
-func SetupLogOutput(logFile *os.File) {
-	// Перенаправляем вывод логов в файл и в консоль
-	log.SetOutput(logFile)
-	// Формат вывода: 2024/10/31 12:34:56 main.go:10: Это логовое сообщение
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+// Level возвращает текущий уровень логирования.
+func Level() slog.Level {
+	return level.Level()
 }
 
-// Сохраняет логи из памяти в файл.
-func SaveLogsToFile(logFile *os.File) {
-	if _, err := logFile.Write(logBuffer.Bytes()); err != nil {
-		log.Fatalf("Не удалось записать логи в файл: %v", err)
+// shortSource оставляет в источнике сообщения только имя файла и строку, как это делал флаг log.Lshortfile.
+func shortSource(_ []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.SourceKey {
+		return a
 	}
-	if err := logFile.Close(); err != nil {
-		log.Fatalf("Не удалось закрыть файл логов: %v", err)
+	if src, ok := a.Value.Any().(*slog.Source); ok {
+		return slog.String(slog.SourceKey, filepath.Base(src.File)+":"+strconv.Itoa(src.Line))
 	}
+	return a
 }
@@ -0,0 +1,34 @@
+package logger_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"3code/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter - вывод, запись в который всегда заканчивается ошибкой, как в закрытый stderr.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, os.ErrClosed
+}
+
+func TestTeeIgnoresMirrorErrors(t *testing.T) {
+	var file, console bytes.Buffer
+
+	w := logger.Tee(&file, failingWriter{}, &console)
+	n, err := w.Write([]byte("запись\n"))
+	require.NoError(t, err, "ошибка копии не должна мешать записи в файл")
+	assert.Equal(t, len("запись\n"), n)
+	assert.Equal(t, "запись\n", file.String())
+	assert.Equal(t, "запись\n", console.String(), "сломанная копия не мешает остальным")
+
+	// Ошибка основного вывода возвращается
+	_, err = logger.Tee(failingWriter{}, &console).Write([]byte("x"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Форматы имен файлов логов и даты, по смене которой файл ротируется
+const (
+	logFileLayout = "2006-01-02_15-04-05"
+	logDayLayout  = "2006-01-02"
+)
+
+// RotateOptions - правила ротации и хранения файлов логов.
+type RotateOptions struct {
+	MaxSize  int64         // Размер файла в байтах, после которого начинается новый файл
+	MaxFiles int           // Сколько файлов логов хранить, включая текущий
+	MaxAge   time.Duration // Файлы старше этого возраста удаляются
+	Compress bool          // Сжимать ли закрытые файлы в .gz
+}
+
+// RotatingWriter пишет логи в файл в директории dir и начинает новый файл
+// при превышении MaxSize или при смене даты. Старые файлы удаляются по количеству и возрасту.
+type RotatingWriter struct {
+	mu   sync.Mutex
+	dir  string
+	opts RotateOptions
+
+	file *os.File
+	size int64
+	day  string // Дата, за которую пишется текущий файл
+
+	// Имя без расширения и номер последнего файла, открытого в ту же секунду
+	base string
+	seq  int
+
+	// Сжатие выполняется в фоне, Close дожидается его завершения.
+	// pending - имена файлов, которые сейчас сжимаются: prune их не трогает
+	compressing sync.WaitGroup
+	pending     map[string]bool
+
+	now func() time.Time
+}
+
+// NewRotatingWriter открывает новый файл логов в директории dir.
+func NewRotatingWriter(dir string, opts RotateOptions) (*RotatingWriter, error) {
+	w := &RotatingWriter{dir: dir, opts: opts, now: time.Now}
+	if err := w.openNew(); err != nil {
+		return nil, err
+	}
+	w.prune()
+	return w, nil
+}
+
+// Write записывает p в текущий файл, при необходимости предварительно ротируя его.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	sizeExceeded := w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize
+	if sizeExceeded || w.now().Format(logDayLayout) != w.day {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Name возвращает путь к текущему файлу логов.
+func (w *RotatingWriter) Name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return ""
+	}
+	return w.file.Name()
+}
+
+// Sync сбрасывает на диск все, что уже записано в текущий файл.
+func (w *RotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close закрывает текущий файл и дожидается окончания фонового сжатия.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = errors.Join(w.file.Sync(), w.file.Close())
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	w.compressing.Wait()
+	return err
+}
+
+// rotate закрывает текущий файл, открывает новый и удаляет лишние старые файлы. Вызывается под блокировкой.
+func (w *RotatingWriter) rotate() error {
+	old := w.file.Name()
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("не удалось закрыть файл логов %s: %w", old, err)
+	}
+	w.file = nil
+
+	if err := w.openNew(); err != nil {
+		return err
+	}
+
+	if w.opts.Compress {
+		if w.pending == nil {
+			w.pending = make(map[string]bool)
+		}
+		w.pending[filepath.Base(old)] = true
+		w.compressing.Add(1)
+		go func() {
+			defer w.compressing.Done()
+			if err := compressFile(old); err != nil {
+				// Логгер здесь использовать нельзя: мы внутри него. Сообщаем в stderr.
+				fmt.Fprintf(os.Stderr, "Не удалось сжать файл логов %s: %v\n", old, err)
+			}
+			w.mu.Lock()
+			delete(w.pending, filepath.Base(old))
+			w.prune()
+			w.mu.Unlock()
+		}()
+	}
+
+	w.prune()
+	return nil
+}
+
+// openNew создает новый файл логов с текущей датой и временем в имени.
+func (w *RotatingWriter) openNew() error {
+	now := w.now()
+	base := filepath.Join(w.dir, now.Format(logFileLayout))
+
+	// При ротации по размеру несколько файлов могут появиться в одну секунду. Номер только растет,
+	// чтобы имя удаленного старого файла не досталось новому и порядок по имени не нарушился
+	if base != w.base {
+		w.base, w.seq = base, 0
+	}
+	name := base + ".log"
+	for w.seq > 0 || fileExists(name) || fileExists(name+".gz") {
+		w.seq++
+		name = fmt.Sprintf("%s_%03d.log", base, w.seq)
+		if !fileExists(name) && !fileExists(name+".gz") {
+			break
+		}
+	}
+
+	// os.O_CREATE: флаг указывает, что если файл не существует, он должен быть создан.
+	// os.O_WRONLY: флаг указывает, что файл будет открыт только для записи.
+	// os.O_APPEND: флаг указывает, что данные добавляются в конец файла, а не перезаписывают его содержимое.
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("%w: не удалось открыть или создать файл логов: %w", ErrLogSetup, err)
+	}
+
+	w.file = file
+	w.size = 0
+	w.day = now.Format(logDayLayout)
+	return nil
+}
+
+// prune удаляет файлы логов сверх MaxFiles и старше MaxAge. Текущий файл и файлы, которые еще сжимаются,
+// не удаляются. Файл и его сжатая копия (X.log и X.log.gz) считаются одним файлом. Вызывается под блокировкой.
+func (w *RotatingWriter) prune() {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return
+	}
+
+	current := ""
+	if w.file != nil {
+		current = filepath.Base(w.file.Name())
+	}
+
+	// Имена начинаются с даты и времени, поэтому сортировка по имени - это сортировка по времени создания
+	files := make(map[string][]string) // Имя без .gz - имена на диске
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")) {
+			continue
+		}
+		log := strings.TrimSuffix(name, ".gz")
+		if log == current {
+			continue
+		}
+		if files[log] == nil {
+			names = append(names, log)
+		}
+		files[log] = append(files[log], name)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(names)))
+
+	cutoff := w.now().Add(-w.opts.MaxAge)
+	for i, log := range names {
+		// Когда сжатие закончится, prune вызовется снова и удалит файл, если он лишний
+		if w.pending[log] {
+			continue
+		}
+
+		// Место для текущего файла уже занято, поэтому старых оставляем на один меньше
+		tooMany := w.opts.MaxFiles > 0 && i >= w.opts.MaxFiles-1
+		tooOld := false
+		if w.opts.MaxAge > 0 {
+			if info, err := os.Stat(filepath.Join(w.dir, files[log][0])); err == nil {
+				tooOld = info.ModTime().Before(cutoff)
+			}
+		}
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		for _, name := range files[log] {
+			if err := os.Remove(filepath.Join(w.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Не удалось удалить старый файл логов %s: %v\n", name, err)
+			}
+		}
+	}
+}
+
+// compressFile сжимает файл в name.gz и удаляет исходный файл.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := errors.Join(gz.Close(), dst.Close()); err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	src.Close()
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,116 @@
+package logger_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"3code/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logFiles(t *testing.T, dir, suffix string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), suffix) {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+func TestRotatingWriterRotatesBySizeAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logger.NewRotatingWriter(dir, logger.RotateOptions{MaxSize: 10, MaxFiles: 3})
+	require.NoError(t, err)
+
+	first := w.Name()
+	for i := 0; i < 5; i++ {
+		_, err := w.Write([]byte("0123456789"))
+		require.NoError(t, err)
+	}
+	assert.NotEqual(t, first, w.Name(), "после переполнения должен открыться новый файл")
+	require.NoError(t, w.Close())
+
+	assert.Len(t, logFiles(t, dir, ".log"), 3, "лишние файлы удаляются")
+
+	// Файл после закрытия не принимает запись
+	_, err = w.Write([]byte("x"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingWriterCompresses(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logger.NewRotatingWriter(dir, logger.RotateOptions{MaxSize: 5, MaxFiles: 10, Compress: true})
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("первая"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("вторая"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Len(t, logFiles(t, dir, ".log"), 1, "открытым остается только текущий файл")
+	archives := logFiles(t, dir, ".log.gz")
+	require.Len(t, archives, 1)
+
+	f, err := os.Open(filepath.Join(dir, archives[0]))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "первая", string(data))
+}
+
+func TestRotatingWriterPruneCountsCompressedOnce(t *testing.T) {
+	dir := t.TempDir()
+	// Прерванное сжатие оставило и файл, и его сжатую копию: это один файл, а не два
+	for _, name := range []string{"2020-01-01_00-00-00.log.gz", "2020-01-02_00-00-00.log", "2020-01-02_00-00-00.log.gz"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644))
+	}
+
+	w, err := logger.NewRotatingWriter(dir, logger.RotateOptions{MaxFiles: 3})
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.FileExists(t, filepath.Join(dir, "2020-01-01_00-00-00.log.gz"), "вместе с текущим хранится ровно три файла")
+	assert.FileExists(t, filepath.Join(dir, "2020-01-02_00-00-00.log"))
+	assert.FileExists(t, filepath.Join(dir, "2020-01-02_00-00-00.log.gz"))
+}
+
+func TestRotatingWriterPruneSkipsCompressing(t *testing.T) {
+	dir := t.TempDir()
+	w, err := logger.NewRotatingWriter(dir, logger.RotateOptions{MaxSize: 5, MaxFiles: 2, Compress: true})
+	require.NoError(t, err)
+
+	// Ротации идут одна за другой, пока предыдущие файлы еще сжимаются
+	for i := 0; i < 50; i++ {
+		_, err := w.Write([]byte("строка"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	archives := logFiles(t, dir, ".log.gz")
+	assert.LessOrEqual(t, len(archives)+len(logFiles(t, dir, ".log")), 2)
+	for _, name := range archives {
+		f, err := os.Open(filepath.Join(dir, name))
+		require.NoError(t, err)
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err, "сжатый файл %s не должен быть недописанным", name)
+		data, err := io.ReadAll(gz)
+		require.NoError(t, err)
+		assert.Equal(t, "строка", string(data))
+		f.Close()
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"3code/config"
+	"3code/database"
+	"3code/lifecycle"
+	"3code/logger"
+	"3code/server"
+)
+
+// Коды завершения процесса. По ним скрипты запуска понимают, имеет ли смысл повторять попытку.
+const (
+	exitOK         = 0
+	exitError      = 1 // Прочие ошибки
+	exitUsage      = 2 // Неверные аргументы командной строки
+	exitConfig     = 3 // Не найдена или некорректна конфигурация
+	exitPermission = 4 // Нет прав на файлы базы данных или логов
+	exitDatabase   = 5 // База данных недоступна или не удалось применить миграции
+)
+
+func main() {
+	err := run(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		// Справку по флагам уже напечатал пакет flag
+		fmt.Println(usage)
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		// Ошибку показываем в консоли, а не только в файле логов
+		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// run выполняет программу и возвращает ошибку вместо завершения процесса, решение о коде выхода принимает main.
+func run(args []string) error {
+	// До настройки логгера записи накапливаются в памяти. Если до настройки дело не дойдет, они выводятся в консоль
+	logger.Bootstrap()
+	defer logger.FlushPending()
+
+	// Флаги идут до имени команды, после них остаются команда и ее аргументы.
+	// Исходные аргументы нужны, чтобы при перезагрузке настроек флаги по-прежнему были важнее файлов .env
+	flagArgs := args
+	cfg, args, err := config.Load(flagArgs)
+	if err != nil {
+		return err
+	}
+
+	logFile, err := logger.InitLogger(cfg.Logger)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cfg.LogSummary()
+
+	// Без аргументов запускаем сервер, иначе выполняем служебную команду
+	if len(args) > 0 {
+		return runCommand(cfg, args[0], args[1:])
+	}
+
+	// Пока сервер работает, команда restore не заменит файл базы данных. Отметку ставим до открытия базы,
+	// иначе файл могли бы подменить между открытием и миграциями
+	release, err := database.HoldDatabase(cfg.Database.File)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	db, err := database.SetupDatabase(cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	// Порядок остановки: сервер добавляет свои шаги (снятие готовности и завершение запросов) в New,
+	// после них сбрасываем логи на диск и закрываем базу данных. Шаг, не уложившийся в таймаут, продолжает
+	// работать одновременно со следующими, но db.Close дожидается уже начатых запросов к базе
+	lc := lifecycle.New(cfg.Server.ShutdownTimeout)
+	backups := database.NewBackups(db, cfg.Database.BackupDir, cfg.Database.BackupKeep)
+	srv, err := server.New(cfg.Server,
+		server.WithStore(database.NewSQLiteStore(db)),
+		server.WithDB(db),
+		server.WithLifecycle(lc),
+		server.WithFrontend(embeddedFrontend()),
+		server.WithBackups(backups),
+		server.WithReload(cfg, func() (config.Config, error) {
+			next, _, err := config.Load(flagArgs)
+			return next, err
+		}),
+	)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	if cfg.Database.BackupInterval > 0 {
+		// Копия, которая делается в момент остановки, прерывается: база вот-вот будет закрыта
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			backups.Run(ctx, cfg.Database.BackupInterval)
+		}()
+		lc.Add("остановка резервного копирования", 0, func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+	lc.Add("сброс логов", 0, func(context.Context) error { return logFile.Sync() })
+	lc.Add("закрытие базы данных", 0, func(context.Context) error { return db.Close() })
+
+	return srv.Run(context.Background())
+}
+
+// exitCode сопоставляет ошибку коду завершения процесса.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, config.ErrConfigMissing), errors.Is(err, config.ErrConfigInvalid):
+		return exitConfig
+	case errors.Is(err, database.ErrDBPermission), errors.Is(err, fs.ErrPermission):
+		return exitPermission
+	case errors.Is(err, database.ErrDBUnavailable), errors.Is(err, database.ErrMigration), errors.Is(err, database.ErrDatabaseInUse):
+		return exitDatabase
+	}
+	return exitError
+}
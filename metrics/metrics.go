@@ -0,0 +1,179 @@
+// Пакет metrics - минимальная реализация метрик в текстовом формате Prometheus
+// (https://prometheus.io/docs/instrumenting/exposition_formats/) без внешних зависимостей.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type - тип метрики в строке # TYPE.
+type Type string
+
+const (
+	TypeCounter   Type = "counter"
+	TypeGauge     Type = "gauge"
+	TypeHistogram Type = "histogram"
+)
+
+// DefBuckets - границы корзин гистограммы времени обработки запросов в секундах.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample - одно значение метрики с набором значений меток в порядке, заданном при регистрации.
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// metric - зарегистрированная метрика, умеющая вывести себя в текстовом формате.
+type metric interface {
+	write(w io.Writer) error
+}
+
+// Registry хранит метрики и выводит их все разом. Метрики выводятся в порядке регистрации.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry создает пустой реестр метрик.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register добавляет метрику. Повторная регистрация имени - ошибка программиста, поэтому паника.
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: метрика %s уже зарегистрирована", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Write выводит все метрики в текстовом формате Prometheus.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler возвращает обработчик, отдающий метрики по HTTP.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var b strings.Builder
+		if err := r.Write(&b); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		io.WriteString(w, b.String())
+	})
+}
+
+// desc - общие для всех метрик имя, описание, тип и имена меток.
+type desc struct {
+	name   string
+	help   string
+	typ    Type
+	labels []string
+}
+
+func (d desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+	return err
+}
+
+// writeSample выводит одну строку значения. extra - дополнительная метка (le у гистограмм).
+func (d desc) writeSample(w io.Writer, suffix string, values []string, extraName, extraValue string, v float64) error {
+	var b strings.Builder
+	b.WriteString(d.name)
+	b.WriteString(suffix)
+
+	if len(values) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, name := range d.labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(name)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(values[i]))
+			b.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(d.labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(extraName)
+			b.WriteString(`="`)
+			b.WriteString(extraValue)
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// checkLabels паникует, если количество значений меток не совпадает с количеством меток.
+func (d desc) checkLabels(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s ожидает %d значений меток, передано %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// vecKey склеивает значения меток в ключ карты.
+func vecKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys возвращает ключи карты по возрастанию, чтобы вывод был стабильным.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpReplacer.Replace(s) }
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
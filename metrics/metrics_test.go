@@ -0,0 +1,76 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"3code/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryTextFormat(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	requests := reg.NewCounterVec("requests_total", "Запросы.", "method", "path")
+	requests.Inc("GET", "/a")
+	requests.Add(2, "GET", "/a")
+	requests.Inc("POST", `/"b"`)
+
+	inFlight := reg.NewGauge("in_flight", "Запросы в обработке.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	duration := reg.NewHistogramVec("duration_seconds", "Время.", []float64{1, 0.1}, "method")
+	duration.Observe(0.05, "GET")
+	duration.Observe(0.5, "GET")
+	duration.Observe(5, "GET")
+
+	reg.NewFunc("tasks", "Задачи\nпо типу.", metrics.TypeGauge, []string{"repeat"}, func() []metrics.Sample {
+		return []metrics.Sample{{LabelValues: []string{"y"}, Value: 1}, {LabelValues: []string{"d"}, Value: 3}}
+	})
+
+	var b strings.Builder
+	require.NoError(t, reg.Write(&b))
+	assert.Equal(t, `# HELP requests_total Запросы.
+# TYPE requests_total counter
+requests_total{method="GET",path="/a"} 3
+requests_total{method="POST",path="/\"b\""} 1
+# HELP in_flight Запросы в обработке.
+# TYPE in_flight gauge
+in_flight 1
+# HELP duration_seconds Время.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="GET",le="0.1"} 1
+duration_seconds_bucket{method="GET",le="1"} 2
+duration_seconds_bucket{method="GET",le="+Inf"} 3
+duration_seconds_sum{method="GET"} 5.55
+duration_seconds_count{method="GET"} 3
+# HELP tasks Задачи\nпо типу.
+# TYPE tasks gauge
+tasks{repeat="d"} 3
+tasks{repeat="y"} 1
+`, b.String())
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewGauge("up", "")
+	assert.Panics(t, func() { reg.NewGauge("up", "") })
+	assert.Panics(t, func() { reg.NewCounterVec("other", "", "a").Inc() }, "количество значений меток проверяется")
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+	reg.NewGauge("up", "Сервер работает.").Set(1)
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, rec.Body.String(), "\nup 1\n")
+}
@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// CounterVec - набор счетчиков, различающихся значениями меток.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*Sample
+}
+
+// NewCounterVec регистрирует счетчик с метками labels.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, TypeCounter, labels}, values: make(map[string]*Sample)}
+	r.register(name, c)
+	return c
+}
+
+// Inc увеличивает на единицу счетчик с метками values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add увеличивает на v счетчик с метками values. Отрицательные v игнорируются: счетчик только растет.
+func (c *CounterVec) Add(v float64, values ...string) {
+	c.checkLabels(values)
+	if v < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := vecKey(values)
+	s, ok := c.values[key]
+	if !ok {
+		s = &Sample{LabelValues: append([]string(nil), values...)}
+		c.values[key] = s
+	}
+	s.Value += v
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		if err := c.writeSample(w, "", s.LabelValues, "", "", s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge - значение, которое может расти и уменьшаться.
+type Gauge struct {
+	desc
+	mu    sync.Mutex
+	value float64
+}
+
+// NewGauge регистрирует метрику-значение без меток.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, typ: TypeGauge}}
+	r.register(name, g)
+	return g
+}
+
+// Set задает значение.
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.value = v
+	g.mu.Unlock()
+}
+
+// Add прибавляет v к значению, v может быть отрицательным.
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.value += v
+	g.mu.Unlock()
+}
+
+// Inc увеличивает значение на единицу.
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec уменьшает значение на единицу.
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) write(w io.Writer) error {
+	g.mu.Lock()
+	v := g.value
+	g.mu.Unlock()
+
+	if err := g.writeHeader(w); err != nil {
+		return err
+	}
+	return g.writeSample(w, "", nil, "", "", v)
+}
+
+// HistogramVec - набор гистограмм, различающихся значениями меток.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	labelValues []string
+	counts      []uint64 // Количество наблюдений, попавших в каждую корзину (не накопительно)
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec регистрирует гистограмму с границами корзин buckets и метками labels.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{desc: desc{name, help, TypeHistogram, labels}, buckets: buckets, values: make(map[string]*histogram)}
+	r.register(name, h)
+	return h
+}
+
+// Observe добавляет наблюдение v в гистограмму с метками values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.checkLabels(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := vecKey(values)
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{labelValues: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+
+	// Первая корзина, в верхнюю границу которой попадает v. Если такой нет, наблюдение учитывается только в +Inf
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hist.counts[i]++
+	}
+	hist.count++
+	hist.sum += v
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hist.counts[i]
+			if err := h.writeSample(w, "_bucket", hist.labelValues, "le", formatFloat(bound), float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		if err := h.writeSample(w, "_bucket", hist.labelValues, "le", "+Inf", float64(hist.count)); err != nil {
+			return err
+		}
+		if err := h.writeSample(w, "_sum", hist.labelValues, "", "", hist.sum); err != nil {
+			return err
+		}
+		if err := h.writeSample(w, "_count", hist.labelValues, "", "", float64(hist.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcMetric - метрика, значения которой вычисляются при каждом выводе.
+type funcMetric struct {
+	desc
+	collect func() []Sample
+}
+
+// NewFunc регистрирует метрику типа typ (счетчик или значение), значения которой возвращает collect
+// при каждом запросе метрик. Удобно для данных, которые уже считает кто-то другой, например sql.DBStats.
+func (r *Registry) NewFunc(name, help string, typ Type, labels []string, collect func() []Sample) {
+	r.register(name, &funcMetric{desc: desc{name, help, typ, labels}, collect: collect})
+}
+
+func (f *funcMetric) write(w io.Writer) error {
+	samples := f.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return vecKey(samples[i].LabelValues) < vecKey(samples[j].LabelValues)
+	})
+
+	if err := f.writeHeader(w); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		f.checkLabels(s.LabelValues)
+		if err := f.writeSample(w, "", s.LabelValues, "", "", s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateFormat - формат даты, в котором задачи хранятся в колонке date таблицы scheduler.
+const DateFormat = "20060102"
+
+// Ограничения на значения правил повторения
+const (
+	maxDailyInterval = 400
+	maxRuleLength    = 128
+	// Сколько дней максимум перебираем при поиске даты для правил w и m.
+	// Пять лет с запасом покрывают любое корректное сочетание дней и месяцев.
+	searchLimitDays = 366 * 5
+)
+
+// Kind - тип правила повторения.
+type Kind string
+
+const (
+	Daily   Kind = "d" // d <число дней>
+	Yearly  Kind = "y" // y
+	Weekly  Kind = "w" // w <дни недели через запятую>
+	Monthly Kind = "m" // m <дни месяца через запятую> [<месяцы через запятую>]
+)
+
+// ErrEmptyRule возвращается, если правило повторения не задано.
+var ErrEmptyRule = errors.New("правило повторения не задано")
+
+// RuleError описывает ошибку разбора правила повторения.
+type RuleError struct {
+	Rule   string // Исходная строка правила
+	Reason string // Причина, по которой правило не принято
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("некорректное правило повторения %q: %s", e.Rule, e.Reason)
+}
+
+// Rule - разобранное правило повторения задачи.
+type Rule struct {
+	Kind Kind
+	// Interval - интервал в днях для правила d
+	Interval int
+	// Weekdays - дни недели для правила w (1 - понедельник, 7 - воскресенье)
+	Weekdays []int
+	// MonthDays - дни месяца для правила m (-1 - последний день, -2 - предпоследний)
+	MonthDays []int
+	// Months - месяцы для правила m (пусто - любой месяц)
+	Months []int
+}
+
+// Parse разбирает строку правила повторения из колонки repeat.
+func Parse(repeat string) (*Rule, error) {
+	repeat = strings.TrimSpace(repeat)
+	if repeat == "" {
+		return nil, ErrEmptyRule
+	}
+	if len(repeat) > maxRuleLength {
+		return nil, &RuleError{Rule: repeat, Reason: fmt.Sprintf("длина правила больше %d символов", maxRuleLength)}
+	}
+
+	fields := strings.Fields(repeat)
+	ruleErr := func(format string, args ...any) error {
+		return &RuleError{Rule: repeat, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	switch Kind(fields[0]) {
+	case Daily:
+		if len(fields) != 2 {
+			return nil, ruleErr("для правила d нужно указать одно число дней")
+		}
+		interval, err := strconv.Atoi(fields[1])
+		if err != nil || interval < 1 || interval > maxDailyInterval {
+			return nil, ruleErr("число дней должно быть от 1 до %d", maxDailyInterval)
+		}
+		return &Rule{Kind: Daily, Interval: interval}, nil
+
+	case Yearly:
+		if len(fields) != 1 {
+			return nil, ruleErr("правило y не принимает параметров")
+		}
+		return &Rule{Kind: Yearly}, nil
+
+	case Weekly:
+		if len(fields) != 2 {
+			return nil, ruleErr("для правила w нужно указать дни недели через запятую")
+		}
+		weekdays, err := parseList(fields[1], func(n int) bool { return n >= 1 && n <= 7 })
+		if err != nil {
+			return nil, ruleErr("день недели %s", err)
+		}
+		return &Rule{Kind: Weekly, Weekdays: weekdays}, nil
+
+	case Monthly:
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, ruleErr("для правила m нужно указать дни месяца и, при необходимости, месяцы")
+		}
+		days, err := parseList(fields[1], func(n int) bool { return (n >= 1 && n <= 31) || n == -1 || n == -2 })
+		if err != nil {
+			return nil, ruleErr("день месяца %s", err)
+		}
+		rule := &Rule{Kind: Monthly, MonthDays: days}
+		if len(fields) == 3 {
+			months, err := parseList(fields[2], func(n int) bool { return n >= 1 && n <= 12 })
+			if err != nil {
+				return nil, ruleErr("месяц %s", err)
+			}
+			rule.Months = months
+			// Иначе Next перебирал бы даты до searchLimitDays, а правило сохранялось бы, так и не сработав
+			for _, day := range days {
+				if day > 0 && !dayInMonths(day, months) {
+					return nil, ruleErr("дня %d нет ни в одном из месяцев %s", day, fields[2])
+				}
+			}
+		}
+		return rule, nil
+	}
+
+	return nil, ruleErr("неизвестный тип правила %q", fields[0])
+}
+
+// dayInMonths сообщает, есть ли день месяца day хотя бы в одном из месяцев months. Февраль считается
+// по високосному году: правило m 29 2 срабатывает раз в четыре года.
+func dayInMonths(day int, months []int) bool {
+	for _, m := range months {
+		if day <= time.Date(2024, time.Month(m)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// parseList разбирает список чисел через запятую и проверяет каждое значение.
+func parseList(list string, valid func(int) bool) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil || !valid(n) {
+			return nil, fmt.Errorf("%q вне допустимого диапазона", part)
+		}
+		values = append(values, n)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+// Next возвращает ближайшую дату повторения, которая строго позже date и строго позже now.
+func (r *Rule) Next(now, date time.Time) (time.Time, error) {
+	now = truncateDay(now)
+	date = truncateDay(date)
+
+	switch r.Kind {
+	case Daily:
+		// Шагаем хотя бы один раз, даже если дата задачи уже в будущем
+		date = date.AddDate(0, 0, r.Interval)
+		for !date.After(now) {
+			date = date.AddDate(0, 0, r.Interval)
+		}
+		return date, nil
+
+	case Yearly:
+		date = date.AddDate(1, 0, 0)
+		for !date.After(now) {
+			date = date.AddDate(1, 0, 0)
+		}
+		return date, nil
+	}
+
+	// Для правил w и m перебираем дни начиная с более поздней из дат
+	start := date
+	if now.After(start) {
+		start = now
+	}
+	for i := 1; i <= searchLimitDays; i++ {
+		day := start.AddDate(0, 0, i)
+		if r.Matches(day) {
+			return day, nil
+		}
+	}
+
+	return time.Time{}, &RuleError{Rule: r.String(), Reason: "не удалось найти подходящую дату"}
+}
+
+// Matches проверяет, подходит ли день под правило w или m.
+// Для правил d и y возвращает false: их даты зависят от исходной даты задачи, а не от календаря.
+func (r *Rule) Matches(day time.Time) bool {
+	switch r.Kind {
+	case Weekly:
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7 // В правилах воскресенье - седьмой день недели
+		}
+		return contains(r.Weekdays, weekday)
+
+	case Monthly:
+		if len(r.Months) > 0 && !contains(r.Months, int(day.Month())) {
+			return false
+		}
+		lastDay := day.AddDate(0, 1, -day.Day()).Day()
+		for _, d := range r.MonthDays {
+			if d == day.Day() || (d < 0 && lastDay+1+d == day.Day()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String восстанавливает строковое представление правила в формате колонки repeat.
+func (r *Rule) String() string {
+	switch r.Kind {
+	case Daily:
+		return fmt.Sprintf("d %d", r.Interval)
+	case Weekly:
+		return "w " + joinInts(r.Weekdays)
+	case Monthly:
+		s := "m " + joinInts(r.MonthDays)
+		if len(r.Months) > 0 {
+			s += " " + joinInts(r.Months)
+		}
+		return s
+	}
+	return string(r.Kind)
+}
+
+// NextDate вычисляет следующую дату задачи по правилу повторения.
+// now - текущая дата, date - исходная дата задачи в формате 20060102, repeat - правило повторения.
+func NextDate(now time.Time, date string, repeat string) (string, error) {
+	start, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return "", fmt.Errorf("некорректная дата %q: %w", date, err)
+	}
+
+	rule, err := Parse(repeat)
+	if err != nil {
+		return "", err
+	}
+
+	next, err := rule.Next(now, start)
+	if err != nil {
+		return "", err
+	}
+	return next.Format(DateFormat), nil
+}
+
+// truncateDay отбрасывает время суток, оставляя только дату.
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func contains(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
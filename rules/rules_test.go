@@ -0,0 +1,107 @@
+package rules_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"3code/rules"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDate(t *testing.T) {
+	now, err := time.Parse(rules.DateFormat, "20240126")
+	require.NoError(t, err)
+
+	tests := []struct {
+		date   string
+		repeat string
+		want   string
+	}{
+		{"20240113", "d 7", "20240127"},
+		{"20240120", "d 20", "20240209"},
+		{"20240202", "d 30", "20240303"},
+		{"20240126", "d 1", "20240127"},
+		{"20231106", "y", "20241106"},
+		{"20240229", "y", "20250301"},
+		{"20240126", "w 1,2,3", "20240129"},
+		{"20240126", "w 7", "20240128"},
+		{"20240125", "w 4,5", "20240201"},
+		{"20240126", "m 25,26,7", "20240207"},
+		{"20240126", "m -1", "20240131"},
+		{"20240126", "m -2", "20240130"},
+		{"20240126", "m 31", "20240131"},
+		{"20240201", "m -1 2,8", "20240229"},
+		{"20231106", "m 13", "20240213"},
+		{"20240409", "m 31 4,8", "20240831"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date+" "+tt.repeat, func(t *testing.T) {
+			got, err := rules.NextDate(now, tt.date, tt.repeat)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNextDateInvalid(t *testing.T) {
+	now := time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		date   string
+		repeat string
+	}{
+		{"20240126", "d"},
+		{"20240126", "d 401"},
+		{"20240126", "d -1"},
+		{"20240126", "y 1"},
+		{"20240126", "w"},
+		{"20240126", "w 0"},
+		{"20240126", "w 1,8"},
+		{"20240126", "m 0"},
+		{"20240126", "m -3"},
+		{"20240126", "m 32"},
+		{"20240126", "m 1 13"},
+		{"20240126", "k 34"},
+		{"20240126", "m 31 2"},
+		{"2024012", "d 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date+" "+tt.repeat, func(t *testing.T) {
+			_, err := rules.NextDate(now, tt.date, tt.repeat)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := rules.NextDate(now, "20240126", "")
+	assert.ErrorIs(t, err, rules.ErrEmptyRule)
+
+	_, err = rules.NextDate(now, "20240126", "x 1")
+	var ruleErr *rules.RuleError
+	require.True(t, errors.As(err, &ruleErr))
+	assert.Equal(t, "x 1", ruleErr.Rule)
+}
+
+func TestParseRejectsImpossibleMonthDays(t *testing.T) {
+	for _, repeat := range []string{"m 31 2", "m 30 2", "m 30,31 2", "m 1,31 2", "m 31 4,6,9,11"} {
+		_, err := rules.Parse(repeat)
+		var ruleErr *rules.RuleError
+		assert.True(t, errors.As(err, &ruleErr), "правило %q никогда не срабатывает", repeat)
+	}
+	for _, repeat := range []string{"m 29 2", "m 31 1,2", "m -1,-2 2", "m 30 2,4"} {
+		_, err := rules.Parse(repeat)
+		assert.NoError(t, err, repeat)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	for _, repeat := range []string{"d 5", "y", "w 1,3,5", "m -1,15", "m -1,1,15 1,6"} {
+		rule, err := rules.Parse(repeat)
+		require.NoError(t, err)
+		assert.Equal(t, repeat, rule.String())
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"3code/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenCookie - имя cookie, в которой клиент передает токен.
+const tokenCookie = "token"
+
+// signinRequest - тело запроса POST /api/signin.
+type signinRequest struct {
+	Password string `json:"password"`
+}
+
+// tokenResponse - тело ответа POST /api/signin.
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// tokenClaims - содержимое токена. Hash - отпечаток пароля, с которым токен был выдан,
+// поэтому после смены TODO_PASSWORD все ранее выданные токены перестают проходить проверку.
+type tokenClaims struct {
+	Hash string `json:"hash"`
+	jwt.RegisteredClaims
+}
+
+// errNoJWTSecret - пароль задан, а секрет подписи токенов нет.
+var errNoJWTSecret = errors.New("при заданном TODO_PASSWORD нужен TODO_JWT_SECRET")
+
+// checkAuthConfig проверяет настройки аутентификации. Подписывать токены паролем нельзя:
+// по любому выданному токену пароль можно было бы подобрать перебором без обращения к серверу.
+func checkAuthConfig(cfg config.Server) error {
+	if cfg.Password != "" && cfg.JWTSecret == "" {
+		return errNoJWTSecret
+	}
+	return nil
+}
+
+// signingKey возвращает ключ подписи токенов. При заданном пароле TODO_JWT_SECRET всегда задан, это проверяют New и Reload.
+func (s *Server) signingKey() []byte {
+	return []byte(s.config().JWTSecret)
+}
+
+// passwordHash вычисляет отпечаток пароля, который кладется в токен.
+// Используем HMAC с ключом подписи, чтобы по токену нельзя было подобрать пароль простым перебором SHA-256.
+func (s *Server) passwordHash(pass string) string {
+	mac := hmac.New(sha256.New, s.signingKey())
+	mac.Write([]byte(pass))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// feedToken возвращает токен ссылки на календарь задач. Календарные приложения не передают cookie,
+// поэтому токен передается в параметре token. Срока действия у него нет, но он меняется вместе с паролем.
+func (s *Server) feedToken() string {
+	mac := hmac.New(sha256.New, s.signingKey())
+	mac.Write([]byte("ics-feed:" + s.config().Password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// createToken выпускает подписанный токен для текущего пароля.
+func (s *Server) createToken(now time.Time) (string, error) {
+	claims := tokenClaims{
+		Hash: s.passwordHash(s.config().Password),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config().TokenTTL)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.signingKey())
+}
+
+// validateToken проверяет подпись, срок действия токена и то, что он выдан для текущего пароля.
+func (s *Server) validateToken(tokenString string) error {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (any, error) {
+		return s.signingKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return fmt.Errorf("некорректный токен: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Hash), []byte(s.passwordHash(s.config().Password))) != 1 {
+		return errors.New("токен выдан для другого пароля")
+	}
+	return nil
+}
+
+// signinHandler обрабатывает POST /api/signin: проверяет пароль и выдает токен.
+func (s *Server) signinHandler(w http.ResponseWriter, r *http.Request) {
+	if s.config().Password == "" {
+		writeError(w, http.StatusBadRequest, "аутентификация отключена: пароль не задан")
+		return
+	}
+
+	var req signinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "ошибка десериализации JSON")
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.Password), []byte(s.config().Password)) != 1 {
+		slog.Warn("Неудачная попытка входа", "remote_addr", r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, "неверный пароль")
+		return
+	}
+
+	now := time.Now()
+	token, err := s.createToken(now)
+	if err != nil {
+		slog.Error("Ошибка подписи токена", "error", err)
+		writeError(w, http.StatusInternalServerError, "не удалось выдать токен")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  now.Add(s.config().TokenTTL),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	writeJSON(w, http.StatusOK, tokenResponse{Token: token})
+}
+
+// authMiddleware пропускает запрос дальше только с действительным токеном в cookie token.
+// Если пароль не задан, аутентификация отключена и все запросы пропускаются.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config().Password == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(tokenCookie)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "требуется аутентификация")
+			return
+		}
+
+		if err := s.validateToken(cookie.Value); err != nil {
+			slog.Warn("Отклонен запрос с недействительным токеном", "method", r.Method, "path", r.URL.Path, "error", err)
+			writeError(w, http.StatusUnauthorized, "требуется аутентификация")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminMiddleware закрывает административные запросы, когда пароль не задан: без аутентификации
+// их мог бы выполнить кто угодно. При заданном пароле запрос уже проверен authMiddleware.
+func (s *Server) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config().Password == "" {
+			writeError(w, http.StatusForbidden, "административные запросы доступны только при заданном TODO_PASSWORD")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// feedAuthMiddleware пропускает запрос с токеном ссылки на календарь в параметре token,
+// а без него проверяет cookie так же, как authMiddleware.
+func (s *Server) feedAuthMiddleware(next http.Handler) http.Handler {
+	cookieAuth := s.authMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" || s.config().Password == "" {
+			cookieAuth.ServeHTTP(w, r)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.feedToken())) != 1 {
+			slog.Warn("Отклонен запрос календаря с недействительным токеном", "remote_addr", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "требуется аутентификация")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
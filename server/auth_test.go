@@ -0,0 +1,62 @@
+package server_test
+
+import (
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"3code/config"
+	"3code/database"
+	"3code/logger"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordChangeRevokesTokens(t *testing.T) {
+	cfg := testConfig()
+	cfg.Password = "старый"
+	next := cfg
+	next.Password = "новый"
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()), server.WithReload(config.Config{Server: cfg}, func() (config.Config, error) {
+		return config.Config{Server: next}, nil
+	}))
+	require.NoError(t, err)
+
+	res := doJSON(t, srv.Handler, http.MethodPost, "/api/signin", `{"password":"старый"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	cookies := res.Cookies()
+	res = doJSON(t, srv.Handler, http.MethodGet, "/api/tasks", "", cookies, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	require.NoError(t, srv.Reload())
+	res = doJSON(t, srv.Handler, http.MethodGet, "/api/tasks", "", cookies, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "токен, выданный до смены пароля, не принимается")
+
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/signin", `{"password":"новый"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = doJSON(t, srv.Handler, http.MethodGet, "/api/tasks", "", res.Cookies(), nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestPasswordRequiresJWTSecret(t *testing.T) {
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	cfg.JWTSecret = ""
+	_, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	assert.ErrorContains(t, err, "TODO_JWT_SECRET")
+
+	// Перезагрузка с паролем без секрета отклоняется, прежние настройки остаются, в том числе уровень логирования
+	prevLevel := logger.Level()
+	t.Cleanup(func() { logger.SetLevel(prevLevel) })
+	logger.SetLevel(slog.LevelWarn)
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()), server.WithReload(config.Config{}, func() (config.Config, error) {
+		return config.Config{Server: cfg, Logger: config.Logger{Level: slog.LevelDebug}}, nil
+	}))
+	require.NoError(t, err)
+	assert.ErrorContains(t, srv.Reload(), "TODO_JWT_SECRET")
+	res := doJSON(t, srv.Handler, http.MethodGet, "/api/tasks", "", nil, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, slog.LevelWarn, logger.Level(), "отклоненная перезагрузка не меняет уровень логирования")
+}
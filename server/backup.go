@@ -0,0 +1,51 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"3code/database"
+)
+
+// WithBackups задает менеджер резервных копий базы данных. Без него маршруты /api/admin/backup* не регистрируются,
+// а без пароля они отвечают 403.
+func WithBackups(b *database.Backups) Option {
+	return func(s *Server) {
+		s.backups = b
+	}
+}
+
+// backupListResponse - тело ответа GET /api/admin/backups.
+type backupListResponse struct {
+	Backups []database.BackupInfo `json:"backups"`
+}
+
+// createBackupHandler обрабатывает POST /api/admin/backup: создает резервную копию базы данных
+// и возвращает ее описание.
+func createBackupHandler(b *database.Backups) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backup, err := b.Create(r.Context())
+		if err != nil {
+			slog.Error("Ошибка резервного копирования базы данных", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось создать резервную копию")
+			return
+		}
+		writeJSON(w, http.StatusCreated, backup)
+	}
+}
+
+// listBackupsHandler обрабатывает GET /api/admin/backups и возвращает резервные копии от старых к новым.
+func listBackupsHandler(b *database.Backups) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backups, err := database.ListBackups(b.Dir())
+		if err != nil {
+			slog.Error("Ошибка получения списка резервных копий", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось получить список резервных копий")
+			return
+		}
+		if backups == nil {
+			backups = []database.BackupInfo{}
+		}
+		writeJSON(w, http.StatusOK, backupListResponse{Backups: backups})
+	}
+}
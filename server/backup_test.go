@@ -0,0 +1,68 @@
+package server_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.OpenDatabase(filepath.Join(dir, "scheduler.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, database.Migrate(db))
+
+	backups := database.NewBackups(db, filepath.Join(dir, "backups"), 3)
+
+	// Без пароля административные запросы мог бы выполнить кто угодно
+	public, err := server.New(testConfig(), server.WithStore(database.NewSQLiteStore(db)), server.WithBackups(backups))
+	require.NoError(t, err)
+	res := doJSON(t, public.Handler, http.MethodPost, "/api/admin/backup", "", nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	res = doJSON(t, public.Handler, http.MethodGet, "/api/admin/backups", "", nil, nil)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	list, err := database.ListBackups(backups.Dir())
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	srv, err := server.New(cfg, server.WithStore(database.NewSQLiteStore(db)), server.WithBackups(backups))
+	require.NoError(t, err)
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/admin/backup", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/signin", `{"password":"секрет"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	cookies := res.Cookies()
+
+	var listed struct {
+		Backups []database.BackupInfo `json:"backups"`
+	}
+	res = doJSON(t, srv.Handler, http.MethodGet, "/api/admin/backups", "", cookies, &listed)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, listed.Backups)
+
+	var created database.BackupInfo
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/admin/backup", "", cookies, &created)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	assert.NotEmpty(t, created.Name)
+	assert.Positive(t, created.Size)
+
+	res = doJSON(t, srv.Handler, http.MethodGet, "/api/admin/backups", "", cookies, &listed)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, listed.Backups, 1)
+	assert.Equal(t, created.Name, listed.Backups[0].Name)
+
+	// Без менеджера резервных копий маршрутов нет
+	plain, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	res = doJSON(t, plain.Handler, http.MethodPost, "/api/admin/backup", "", nil, nil)
+	assert.NotEqual(t, http.StatusCreated, res.StatusCode)
+}
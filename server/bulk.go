@@ -0,0 +1,415 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/database"
+	"3code/rules"
+)
+
+// Форматы выгрузки и загрузки задач
+const (
+	formatCSV    = "csv"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// bulkContentTypes - типы содержимого форматов выгрузки
+var bulkContentTypes = map[string]string{
+	formatCSV:    "text/csv; charset=utf-8",
+	formatJSON:   "application/json; charset=UTF-8",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// csvHeader - колонки CSV в порядке выгрузки, они совпадают с колонками таблицы scheduler.
+var csvHeader = []string{"id", "date", "title", "comment", "repeat"}
+
+// bulkImportReport - тело ответа POST /api/import.
+type bulkImportReport struct {
+	Conflict database.ConflictPolicy `json:"conflict"`
+	Created  int                     `json:"created"`
+	Updated  int                     `json:"updated"`
+	Skipped  int                     `json:"skipped"`
+	// Results - итог по каждой задаче в порядке файла
+	Results []database.ImportResult `json:"results"`
+	// Errors - ошибки в строках файла. Если они есть, ни одна задача не импортируется
+	Errors []rowError `json:"errors"`
+}
+
+// rowError - ошибка в строке импортируемого файла.
+// Row - номер строки для CSV и NDJSON или номер элемента массива tasks для JSON, начиная с 1.
+type rowError struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// importRow - задача из импортируемого файла с номером строки, на которой она записана.
+type importRow struct {
+	row  int
+	task database.Task
+	err  error // Ошибка разбора строки, задача при этом пустая
+}
+
+// exportHandler обрабатывает GET /api/export?format=csv|json|ndjson и выгружает все задачи.
+// Задачи пишутся в ответ по мере чтения из базы. JSON выгружается в том же виде, что и ответ GET /api/tasks.
+// Пустые comment и repeat, записанные в базе как NULL, выгружаются пустой строкой и после загрузки обратно
+// сохраняются как пустая строка: задача не различает эти значения, и во всех форматах они одинаковы.
+func exportHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = formatJSON
+		}
+		contentType, ok := bulkContentTypes[format]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "параметр format должен быть csv, json или ndjson")
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tasks-%s.%s"`, time.Now().Format(rules.DateFormat), format))
+
+		tw := newTaskWriter(format, w)
+		count := 0
+		err := store.Each(r.Context(), func(task database.Task) error {
+			count++
+			return tw.Write(task)
+		})
+		if err == nil {
+			err = tw.Close()
+		}
+		if err != nil {
+			// Заголовки уже отправлены, сообщить клиенту об ошибке статусом нельзя: обрезанный JSON не разберется,
+			// а для CSV и NDJSON об обрыве будет видно только в логе
+			slog.Error("Ошибка выгрузки задач", "format", format, "written", count, "error", err)
+			return
+		}
+		slog.Info("Задачи выгружены", "format", format, "count", count)
+	}
+}
+
+// importHandler обрабатывает POST /api/import?format=csv|json|ndjson&conflict=skip|overwrite|new-id.
+// Формат можно не указывать, тогда он определяется по Content-Type. Все задачи проверяются до записи
+// и добавляются одной транзакцией: при ошибке хотя бы в одной строке не добавляется ничего.
+func importHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = formatFromContentType(r.Header.Get("Content-Type"))
+		}
+		if _, ok := bulkContentTypes[format]; !ok {
+			writeError(w, http.StatusBadRequest, "укажите формат в параметре format: csv, json или ndjson")
+			return
+		}
+
+		policy := database.ConflictPolicy(r.URL.Query().Get("conflict"))
+		switch policy {
+		case "":
+			policy = database.ConflictSkip
+		case database.ConflictSkip, database.ConflictOverwrite, database.ConflictNewID:
+		default:
+			writeError(w, http.StatusBadRequest, "параметр conflict должен быть skip, overwrite или new-id")
+			return
+		}
+
+		rows, err := readTasks(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("файл больше %d байт", maxImportSize))
+			return
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		report := bulkImportReport{Conflict: policy, Results: []database.ImportResult{}, Errors: []rowError{}}
+		tasks := make([]database.Task, 0, len(rows))
+		seen := make(map[string]int)
+		for _, row := range rows {
+			err := row.err
+			if err == nil {
+				err = validateImportedTask(row.task)
+			}
+			if err == nil && row.task.ID != "" {
+				if first, ok := seen[row.task.ID]; ok {
+					err = fmt.Errorf("идентификатор %s уже встречался в строке %d", row.task.ID, first)
+				}
+				seen[row.task.ID] = row.row
+			}
+			if err != nil {
+				report.Errors = append(report.Errors, rowError{Row: row.row, ID: row.task.ID, Error: err.Error()})
+				continue
+			}
+			tasks = append(tasks, row.task)
+		}
+		if len(report.Errors) > 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, report)
+			return
+		}
+
+		results, err := store.Import(r.Context(), tasks, policy)
+		if err != nil {
+			slog.Error("Ошибка импорта задач", "format", format, "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось импортировать задачи")
+			return
+		}
+		report.Results = results
+		for _, res := range results {
+			switch res.Action {
+			case database.ImportCreated:
+				report.Created++
+			case database.ImportUpdated:
+				report.Updated++
+			case database.ImportSkipped:
+				report.Skipped++
+			}
+		}
+
+		slog.Info("Задачи импортированы", "format", format, "conflict", policy,
+			"created", report.Created, "updated", report.Updated, "skipped", report.Skipped)
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// formatFromContentType определяет формат импорта по заголовку Content-Type.
+func formatFromContentType(header string) string {
+	mediaType, _, _ := mime.ParseMediaType(header)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/json":
+		return formatJSON
+	case "application/x-ndjson", "application/jsonl":
+		return formatNDJSON
+	}
+	return ""
+}
+
+// validateImportedTask проверяет задачу из файла. В отличие от validateTask поля не меняются:
+// выгрузка и загрузка обратно должны давать те же задачи, даже если их даты уже прошли.
+func validateImportedTask(task database.Task) error {
+	if task.ID != "" {
+		if id, err := strconv.ParseInt(task.ID, 10, 64); err != nil || id <= 0 {
+			return fmt.Errorf("некорректный идентификатор задачи %q", task.ID)
+		}
+	}
+	if strings.TrimSpace(task.Title) == "" {
+		return errors.New("не указан заголовок задачи")
+	}
+	if _, err := time.Parse(rules.DateFormat, task.Date); err != nil {
+		return fmt.Errorf("дата %q указана в неверном формате, ожидается YYYYMMDD", task.Date)
+	}
+	if task.Repeat != "" {
+		if _, err := rules.Parse(task.Repeat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readTasks читает задачи из файла в формате format. Ошибки в отдельных строках попадают в importRow.err,
+// а ошибка возвращается, только если файл нельзя разобрать целиком.
+func readTasks(format string, r io.Reader) ([]importRow, error) {
+	switch format {
+	case formatCSV:
+		return readCSVTasks(r)
+	case formatNDJSON:
+		return readNDJSONTasks(r)
+	}
+	return readJSONTasks(r)
+}
+
+// readCSVTasks читает CSV с заголовком. Колонки определяются по заголовку и могут идти в любом порядке,
+// обязательны date и title.
+func readCSVTasks(r io.Reader) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("пустой файл CSV")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(csvHeader, name) {
+			return nil, fmt.Errorf("неизвестная колонка CSV %q, ожидаются %s", name, strings.Join(csvHeader, ", "))
+		}
+		if first, ok := columns[name]; ok {
+			// Непонятно, какое из значений верное, поэтому файл не принимается целиком
+			line, _ := cr.FieldPos(i)
+			return []importRow{{row: line, err: fmt.Errorf("колонка %s повторяется в заголовке (колонки %d и %d)", name, first+1, i+1)}}, nil
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"date", "title"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("в CSV нет обязательной колонки %s", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			// Количество колонок не совпало только в этой строке, остальные можно проверять дальше
+			rows = append(rows, importRow{row: parseErr.StartLine, err: fmt.Errorf("ожидается %d колонок, получено %d", len(header), len(record))})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, importRow{row: line, task: database.Task{
+			ID:      field(record, "id"),
+			Date:    field(record, "date"),
+			Title:   field(record, "title"),
+			Comment: field(record, "comment"),
+			Repeat:  field(record, "repeat"),
+		}})
+	}
+}
+
+// readJSONTasks читает объект {"tasks": [...]} в том виде, в каком его выгружает exportHandler.
+func readJSONTasks(r io.Reader) ([]importRow, error) {
+	var body tasksResponse
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		return nil, fmt.Errorf("ошибка разбора JSON: %w", err)
+	}
+
+	rows := make([]importRow, len(body.Tasks))
+	for i, task := range body.Tasks {
+		rows[i] = importRow{row: i + 1, task: task}
+	}
+	return rows, nil
+}
+
+// readNDJSONTasks читает по одной задаче в строке. Пустые строки пропускаются.
+func readNDJSONTasks(r io.Reader) ([]importRow, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxImportSize)
+
+	var rows []importRow
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row := importRow{row: n}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row.task); err != nil {
+			row.task, row.err = database.Task{}, fmt.Errorf("ошибка разбора JSON: %w", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения NDJSON: %w", err)
+	}
+	return rows, nil
+}
+
+// taskWriter пишет задачи в ответ в одном из форматов выгрузки.
+type taskWriter interface {
+	Write(task database.Task) error
+	// Close дописывает окончание файла и сбрасывает буфер
+	Close() error
+}
+
+func newTaskWriter(format string, w io.Writer) taskWriter {
+	switch format {
+	case formatCSV:
+		// Ошибку записи заголовка csv.Writer запоминает и вернет из Close
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		return &csvTaskWriter{w: cw}
+	case formatNDJSON:
+		return &ndjsonTaskWriter{enc: json.NewEncoder(w)}
+	}
+	return &jsonTaskWriter{w: w}
+}
+
+// csvTaskWriter пишет CSV с заголовком csvHeader. Переводы строк и кавычки внутри полей экранирует encoding/csv.
+type csvTaskWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvTaskWriter) Write(task database.Task) error {
+	return c.w.Write([]string{task.ID, task.Date, task.Title, task.Comment, task.Repeat})
+}
+
+func (c *csvTaskWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonTaskWriter пишет {"tasks":[...]} по одной задаче, не собирая массив в памяти.
+type jsonTaskWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonTaskWriter) Write(task database.Task) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	prefix := ","
+	if j.count == 0 {
+		prefix = `{"tasks":[`
+	}
+	j.count++
+	_, err = io.WriteString(j.w, prefix+string(data))
+	return err
+}
+
+func (j *jsonTaskWriter) Close() error {
+	end := "]}\n"
+	if j.count == 0 {
+		end = `{"tasks":[]}` + "\n"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// ndjsonTaskWriter пишет по одной задаче в строке.
+type ndjsonTaskWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonTaskWriter) Write(task database.Task) error {
+	return n.enc.Encode(task)
+}
+
+func (n *ndjsonTaskWriter) Close() error {
+	return nil
+}
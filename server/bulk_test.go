@@ -0,0 +1,119 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := database.NewMemoryStore()
+	_, err := source.Import(ctx, []database.Task{
+		{ID: "3", Date: "20200101", Title: "Прошедшая, \"в кавычках\"", Comment: "строка 1\nстрока 2", Repeat: "m -1 2,8"},
+		{ID: "7", Date: "20240201", Title: "Купить хлеб", Comment: "в булочной; с маком"},
+	}, database.ConflictSkip)
+	require.NoError(t, err)
+	want, err := source.All(ctx)
+	require.NoError(t, err)
+
+	from, err := server.New(testConfig(), server.WithStore(source))
+	require.NoError(t, err)
+
+	for _, format := range []string{"csv", "json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			from.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export?format="+format, nil))
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Header().Get("Content-Disposition"), "."+format+`"`)
+			exported := rec.Body.String()
+
+			target := database.NewMemoryStore()
+			to, err := server.New(testConfig(), server.WithStore(target))
+			require.NoError(t, err)
+
+			var report struct {
+				Created int `json:"created"`
+			}
+			res := doJSON(t, to.Handler, http.MethodPost, "/api/import?format="+format, exported, nil, &report)
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, 2, report.Created)
+
+			got, err := target.All(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "задачи после выгрузки и загрузки не должны меняться")
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	from.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestImportReportsRowErrors(t *testing.T) {
+	store := database.NewMemoryStore()
+	srv, err := server.New(testConfig(), server.WithStore(store))
+	require.NoError(t, err)
+
+	post := func(target, contentType, body string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		data, err := io.ReadAll(rec.Body)
+		require.NoError(t, err)
+		return rec.Result(), string(data)
+	}
+
+	csvBody := "title,date,repeat,id\n" +
+		"Хорошая,20240201,,1\n" +
+		"Плохая дата,01.02.2024,,2\n" +
+		"Повтор,20240201,,1\n" +
+		"Лишняя колонка,20240201,,4,x\n" +
+		"Плохое правило,20240201,z 1,5\n"
+	res, body := post("/api/import", "text/csv", csvBody)
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	for _, want := range []string{`"row":3`, `"row":4`, `"row":5`, `"row":6`, "уже встречался в строке 2"} {
+		assert.Contains(t, body, want)
+	}
+	tasks, err := store.All(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "при ошибках не импортируется ничего")
+
+	res, body = post("/api/import", "application/x-ndjson", `{"date":"20240201","title":"Первая"}`+"\n\n"+`{"date":"20240201","titel":"Опечатка"}`+"\n")
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	assert.Contains(t, body, `"row":3`)
+
+	res, _ = post("/api/import", "text/csv", "name,date\nЗадача,20240201\n")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res, body = post("/api/import", "text/csv", "title,date,title\nПервый,20240201,Второй\n")
+	require.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+	assert.Contains(t, body, `"row":1`)
+	assert.Contains(t, body, "колонка title повторяется")
+	res, _ = post("/api/import", "text/plain", "")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res, _ = post("/api/import?format=json&conflict=replace", "", `{"tasks":[]}`)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// Задача без идентификатора получает новый, существующая обновляется при conflict=overwrite
+	res, body = post("/api/import?conflict=overwrite", "application/json",
+		`{"tasks":[{"id":"1","date":"20240201","title":"Первая"},{"date":"20240202","title":"Вторая"}]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode, body)
+	res, body = post("/api/import?conflict=overwrite", "application/json",
+		`{"tasks":[{"id":"1","date":"20240203","title":"Первая, исправленная"}]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode, body)
+	assert.Contains(t, body, `"updated":1`)
+
+	task, err := store.Get(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Первая, исправленная", task.Title)
+}
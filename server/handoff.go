@@ -0,0 +1,9 @@
+package server
+
+// Переменные окружения, через которые новый процесс при передаче сокета узнает номера переданных ему дескрипторов:
+// слушающего сокета, канала, в который он сообщает о готовности, и сокета перенаправления на HTTPS.
+const (
+	listenFDEnv   = "TODO_LISTEN_FD"
+	readyFDEnv    = "TODO_READY_FD"
+	redirectFDEnv = "TODO_REDIRECT_FD"
+)
@@ -0,0 +1,163 @@
+//go:build linux
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// handoffTimeout - сколько ждать, пока новый процесс начнет принимать соединения.
+// За это время он должен прочитать настройки, открыть базу данных и применить миграции.
+const handoffTimeout = 30 * time.Second
+
+// handoffSignals - сигналы, по которым сокет передается новому процессу.
+var handoffSignals = []os.Signal{syscall.SIGUSR2}
+
+func isHandoffSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR2
+}
+
+// handoff запускает новый экземпляр программы с теми же аргументами и окружением и передает ему слушающий сокет.
+// Возвращает nil, когда новый процесс начал принимать соединения: после этого текущий процесс может останавливаться,
+// соединения при этом не теряются, потому что сокет остается открытым в новом процессе.
+func (s *Server) handoff() error {
+	ln, ok := s.listener.Load().(*net.TCPListener)
+	if !ok {
+		return errors.New("метод handoff: сервер еще не принимает соединения")
+	}
+	// File возвращает копию дескриптора, сам сокет продолжает работать в текущем процессе
+	lnFile, err := ln.File()
+	if err != nil {
+		return fmt.Errorf("метод handoff: ошибка получения дескриптора сокета: %w", err)
+	}
+	defer lnFile.Close()
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("метод handoff: ошибка создания канала готовности: %w", err)
+	}
+	defer readyR.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		readyW.Close()
+		return fmt.Errorf("метод handoff: ошибка определения исполняемого файла: %w", err)
+	}
+
+	// ExtraFiles получают в новом процессе дескрипторы 3, 4, ...
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(handoffEnviron(), listenFDEnv+"=3", readyFDEnv+"=4")
+	cmd.ExtraFiles = []*os.File{lnFile, readyW}
+
+	// Сокет перенаправления на HTTPS передаем так же, иначе новый процесс не сможет занять его порт
+	if redirect, ok := s.redirectListener.Load().(*net.TCPListener); ok {
+		redirectFile, err := redirect.File()
+		if err != nil {
+			readyW.Close()
+			return fmt.Errorf("метод handoff: ошибка получения дескриптора сокета перенаправления: %w", err)
+		}
+		defer redirectFile.Close()
+		cmd.Env = append(cmd.Env, redirectFDEnv+"=5")
+		cmd.ExtraFiles = append(cmd.ExtraFiles, redirectFile)
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	err = cmd.Start()
+	// Копия для записи нужна только новому процессу, иначе чтение не увидит конец канала, если он завершится
+	readyW.Close()
+	if err != nil {
+		return fmt.Errorf("метод handoff: ошибка запуска нового процесса: %w", err)
+	}
+	slog.Info("Запущен новый процесс, ждем, пока он начнет принимать соединения", "pid", cmd.Process.Pid)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			// Канал закрылся без сообщения о готовности: скорее всего, новый процесс завершился при запуске
+			cmd.Process.Kill()
+			return fmt.Errorf("метод handoff: новый процесс не сообщил о готовности: %w", <-exited)
+		}
+	case <-time.After(handoffTimeout):
+		cmd.Process.Kill()
+		<-exited
+		return fmt.Errorf("метод handoff: новый процесс не начал работу за %s", handoffTimeout)
+	}
+
+	slog.Info("Новый процесс принимает соединения", "pid", cmd.Process.Pid)
+	return nil
+}
+
+// handoffEnviron возвращает окружение текущего процесса без переменных передачи сокета,
+// которые могли остаться от предыдущей передачи.
+func handoffEnviron() []string {
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, listenFDEnv+"=") || strings.HasPrefix(kv, readyFDEnv+"=") || strings.HasPrefix(kv, redirectFDEnv+"=") {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// inheritedListener возвращает сокет, переданный предыдущим процессом в переменной окружения env, если он есть.
+func inheritedListener(env string) (net.Listener, bool, error) {
+	value, ok := os.LookupEnv(env)
+	if !ok {
+		return nil, false, nil
+	}
+	// Следующая передача из этого процесса задаст переменную заново
+	os.Unsetenv(env)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s=%q: ожидается номер дескриптора", env, value)
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close() // FileListener делает свою копию дескриптора
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, false, err
+	}
+	return ln, true, nil
+}
+
+// notifyReady сообщает предыдущему процессу, что новый процесс начал принимать соединения.
+func notifyReady() {
+	value, ok := os.LookupEnv(readyFDEnv)
+	if !ok {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Error("Некорректный дескриптор канала готовности", "name", readyFDEnv, "value", value)
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		slog.Error("Не удалось сообщить о готовности предыдущему процессу", "error", err)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handoffChildEnv - переменная, по которой тестовый бинарный файл, перезапущенный при передаче сокета,
+// понимает, что он новый процесс. В ней путь к файлу, куда он записывает свой pid.
+const handoffChildEnv = "SERVER_TEST_HANDOFF_PID_FILE"
+
+func TestMain(m *testing.M) {
+	if pidFile := os.Getenv(handoffChildEnv); pidFile != "" && os.Getenv("TODO_LISTEN_FD") != "" {
+		os.Exit(runHandoffChild(pidFile))
+	}
+	os.Exit(m.Run())
+}
+
+// runHandoffChild работает как новый процесс: принимает сокет и обслуживает запросы до SIGTERM.
+func runHandoffChild(pidFile string) int {
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	if err != nil {
+		return 1
+	}
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return 1
+	}
+	if err := srv.Run(context.Background()); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestHandoffToNewProcess(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	t.Setenv(handoffChildEnv, pidFile)
+
+	cfg := testConfig()
+	cfg.Port = freePort(t)
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(context.Background()) }()
+
+	url := "http://127.0.0.1:" + cfg.Port + "/healthz"
+	require.Eventually(t, func() bool {
+		res, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	srv.StopChan <- syscall.SIGUSR2
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("старый процесс не остановился после передачи сокета")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err, "новый процесс должен был запуститься")
+	pid, err := strconv.Atoi(string(data))
+	require.NoError(t, err)
+	child, err := os.FindProcess(pid)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		child.Signal(syscall.SIGTERM)
+	})
+
+	// Старый сервер остановлен, на тот же порт отвечает новый процесс
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
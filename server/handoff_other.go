@@ -0,0 +1,26 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Передача слушающего сокета новому процессу поддерживается только в Linux.
+var handoffSignals []os.Signal
+
+func isHandoffSignal(os.Signal) bool {
+	return false
+}
+
+func (s *Server) handoff() error {
+	return errors.New("метод handoff: передача сокета новому процессу не поддерживается на этой платформе")
+}
+
+func inheritedListener(string) (net.Listener, bool, error) {
+	return nil, false, nil
+}
+
+func notifyReady() {}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// readyPingTimeout - сколько ждать ответа базы данных при проверке готовности.
+const readyPingTimeout = 2 * time.Second
+
+// WithDB задает базу данных, доступность которой проверяет /readyz.
+func WithDB(db *sql.DB) Option {
+	return func(s *Server) {
+		s.db = db
+	}
+}
+
+// healthResponse - тело ответа /healthz и /readyz. Checks содержит результат каждой проверки: "ok" или текст ошибки.
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// versionResponse - тело ответа /version.
+type versionResponse struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go"`
+}
+
+// healthzHandler отвечает 200, пока процесс жив и обрабатывает запросы.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// readyzHandler отвечает 200, если сервер готов принимать запросы: база данных отвечает, директория фронтенда на месте
+// и остановка еще не началась. Иначе отвечает 503 с описанием непройденных проверок.
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := make(map[string]string)
+	ready := true
+	check := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	if s.draining.Load() {
+		check("shutdown", errors.New("сервер останавливается"))
+	}
+	if s.db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readyPingTimeout)
+		defer cancel()
+		check("database", s.db.PingContext(ctx))
+	}
+	// Встроенные файлы фронтенда всегда на месте, проверять нужно только директорию
+	if s.frontend == nil {
+		check("frontend", checkDir(s.config().FrontendDir))
+	}
+
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", Checks: checks})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok", Checks: checks})
+}
+
+// versionHandler возвращает версию модуля и ревизию VCS, с которых собран бинарный файл.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "информация о сборке недоступна")
+		return
+	}
+
+	resp := versionResponse{Version: info.Main.Version, GoVersion: info.GoVersion}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			resp.Revision = setting.Value
+		case "vcs.time":
+			resp.Time = setting.Value
+		case "vcs.modified":
+			resp.Modified = setting.Value == "true"
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// checkDir проверяет, что dir существует и является директорией.
+func checkDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s не является директорией", dir)
+	}
+	return nil
+}
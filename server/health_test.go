@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	db, err := database.OpenDatabase(":memory:")
+	require.NoError(t, err)
+
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()), server.WithDB(db))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	var health struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	res := doJSON(t, h, http.MethodGet, "/healthz", "", nil, &health)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodGet, "/readyz", "", nil, &health)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ok", health.Checks["database"])
+
+	var version struct {
+		GoVersion string `json:"go"`
+	}
+	res = doJSON(t, h, http.MethodGet, "/version", "", nil, &version)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEmpty(t, version.GoVersion)
+
+	// Недоступная база делает сервер не готовым, но не мертвым
+	require.NoError(t, db.Close())
+	res = doJSON(t, h, http.MethodGet, "/readyz", "", nil, &health)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.NotEqual(t, "ok", health.Checks["database"])
+	res = doJSON(t, h, http.MethodGet, "/healthz", "", nil, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestReadyzFailsWhileDraining(t *testing.T) {
+	cfg := testConfig()
+	cfg.DrainDelay = 300 * time.Millisecond
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(context.Background()) }()
+	time.Sleep(100 * time.Millisecond)
+
+	res := doJSON(t, srv.Handler, http.MethodGet, "/readyz", "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	srv.StopChan <- syscall.SIGTERM
+	assert.Eventually(t, func() bool {
+		res := doJSON(t, srv.Handler, http.MethodGet, "/readyz", "", nil, nil)
+		return res.StatusCode == http.StatusServiceUnavailable
+	}, cfg.DrainDelay, 10*time.Millisecond, "готовность снимается до остановки сервера")
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("сервер не остановился после сигнала")
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"3code/database"
+	"3code/ical"
+)
+
+// icsRefresh - как часто календарному приложению обновлять подписку на задачи.
+const icsRefresh = time.Hour
+
+// feedResponse - тело ответа GET /api/feed.
+type feedResponse struct {
+	URL string `json:"url"`
+}
+
+// icsHandler обрабатывает GET /api/tasks.ics и отдает все задачи в формате iCalendar.
+func icsHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tasks, err := store.All(r.Context())
+		if err != nil {
+			slog.Error("Ошибка получения списка задач для календаря", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось получить список задач")
+			return
+		}
+
+		now := time.Now()
+		cal := ical.Calendar{Name: "Задачи", Refresh: icsRefresh, Stamp: now}
+		for _, task := range tasks {
+			events, err := ical.TaskEvents(task, now, now.Add(ical.ExpandHorizon))
+			if err != nil {
+				// Одна испорченная строка не должна ломать весь календарь
+				slog.Warn("Задача пропущена при выгрузке в календарь", "id", task.ID, "error", err)
+				continue
+			}
+			cal.Events = append(cal.Events, events...)
+		}
+
+		// Собираем календарь целиком, чтобы при ошибке успеть ответить 500
+		var buf bytes.Buffer
+		if err := cal.Encode(&buf); err != nil {
+			slog.Error("Ошибка формирования календаря", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось сформировать календарь")
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(buf.Bytes())
+	}
+}
+
+// feedHandler обрабатывает GET /api/feed и возвращает ссылку для подписки на календарь задач.
+// В ссылке есть токен, поэтому ее можно добавить в календарное приложение, которое не умеет входить по паролю.
+func (s *Server) feedHandler(w http.ResponseWriter, r *http.Request) {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	feed := url.URL{Scheme: scheme, Host: r.Host, Path: "/api/tasks.ics"}
+	if s.config().Password != "" {
+		feed.RawQuery = url.Values{"token": {s.feedToken()}}.Encode()
+	}
+	writeJSON(w, http.StatusOK, feedResponse{URL: feed.String()})
+}
@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasksICS(t *testing.T) {
+	store := database.NewMemoryStore()
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("20060102")
+	_, err := store.Add(context.Background(), database.Task{Date: tomorrow, Title: "Полить цветы", Repeat: "d 3"})
+	require.NoError(t, err)
+
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	srv, err := server.New(cfg, server.WithStore(store))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	res := doJSON(t, h, http.MethodGet, "/api/tasks.ics", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = doJSON(t, h, http.MethodGet, "/api/tasks.ics?token=чужой", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodPost, "/api/signin", `{"password":"секрет"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	cookies := res.Cookies()
+
+	var feed struct {
+		URL string `json:"url"`
+	}
+	res = doJSON(t, h, http.MethodGet, "/api/feed", "", cookies, &feed)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	link, err := url.Parse(feed.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/tasks.ics", link.Path)
+	require.NotEmpty(t, link.Query().Get("token"))
+
+	// По ссылке календарь открывается без cookie
+	res = doJSON(t, h, http.MethodGet, link.RequestURI(), "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/calendar; charset=utf-8", res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "SUMMARY:Полить цветы\r\n")
+	assert.Contains(t, string(body), "DTSTART;VALUE=DATE:"+tomorrow+"\r\n")
+	assert.Contains(t, string(body), "RRULE:FREQ=DAILY;INTERVAL=3\r\n")
+
+	// С cookie календарь тоже доступен
+	res = doJSON(t, h, http.MethodGet, "/api/tasks.ics", "", cookies, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// После смены пароля старая ссылка перестает работать
+	cfg.Password = "другой"
+	other, err := server.New(cfg, server.WithStore(store))
+	require.NoError(t, err)
+	res = doJSON(t, other.Handler, http.MethodGet, link.RequestURI(), "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.False(t, strings.Contains(res.Header.Get("Content-Type"), "calendar"))
+}
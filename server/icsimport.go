@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"3code/database"
+	"3code/ical"
+)
+
+// maxImportSize - максимальный размер файла, принимаемого для импорта.
+const maxImportSize = 10 << 20
+
+// ImportReport - результат импорта задач.
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Imported - сколько задач добавлено, а при пробном запуске - сколько было бы добавлено
+	Imported int             `json:"imported"`
+	Tasks    []database.Task `json:"tasks"`
+	Skipped  []SkippedItem   `json:"skipped"`
+}
+
+// SkippedItem описывает запись, которую не удалось перенести в задачи.
+type SkippedItem struct {
+	Line    int    `json:"line"`
+	UID     string `json:"uid,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// ImportICS добавляет в хранилище задачи из событий и задач календаря iCalendar.
+// Задачи проверяются так же, как при добавлении через API: прошедшие даты переносятся на сегодня
+// или на следующую дату по правилу. Все подходящие задачи добавляются одной транзакцией:
+// при ошибке хранилища не добавляется ни одна, и Imported в отчете равно нулю.
+// При dryRun хранилище не меняется, а отчет показывает, что было бы добавлено.
+// Ошибка формата файла оборачивает ical.ErrFormat.
+func ImportICS(ctx context.Context, store database.TaskStore, r io.Reader, now time.Time, dryRun bool) (ImportReport, error) {
+	// Ошибку разбора не оборачиваем: в ней уже есть номер строки, и ее целиком показываем пользователю
+	items, err := ical.Parse(r)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	report := ImportReport{DryRun: dryRun, Tasks: []database.Task{}, Skipped: []SkippedItem{}}
+	for _, item := range items {
+		task, err := ical.ToTask(item)
+		if err == nil {
+			err = validateTask(&task, now)
+		}
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedItem{Line: item.Line, UID: item.UID, Summary: item.Summary, Reason: err.Error()})
+			continue
+		}
+		report.Tasks = append(report.Tasks, task)
+	}
+
+	if !dryRun && len(report.Tasks) > 0 {
+		// У задач нет идентификаторов, поэтому все они добавляются под новыми
+		results, err := store.Import(ctx, report.Tasks, database.ConflictNewID)
+		if err != nil {
+			return report, fmt.Errorf("функция ImportICS: задачи не добавлены: %w", err)
+		}
+		for i, res := range results {
+			report.Tasks[i].ID = res.ID
+		}
+	}
+	report.Imported = len(report.Tasks)
+	return report, nil
+}
+
+// importICSHandler обрабатывает POST /api/import/ics?dry_run= и добавляет задачи из календаря в теле запроса.
+func importICSHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun := false
+		if v := r.URL.Query().Get("dry_run"); v != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(v); err != nil {
+				writeError(w, http.StatusBadRequest, "параметр dry_run должен быть true или false")
+				return
+			}
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+		report, err := ImportICS(r.Context(), store, body, time.Now(), dryRun)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("файл больше %d байт", maxImportSize))
+			return
+		case errors.Is(err, ical.ErrFormat):
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		case err != nil:
+			slog.Error("Ошибка импорта календаря", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось импортировать задачи, ни одна задача не добавлена")
+			return
+		}
+
+		if !dryRun {
+			slog.Info("Импортированы задачи из календаря", "imported", report.Imported, "skipped", len(report.Skipped))
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
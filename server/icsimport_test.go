@@ -0,0 +1,104 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportICS(t *testing.T) {
+	next := time.Now().AddDate(0, 0, 3).Format("20060102")
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:" + next,
+		"SUMMARY:Полить цветы",
+		"RRULE:FREQ=DAILY;INTERVAL=3",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:ends@example",
+		"DTSTART;VALUE=DATE:" + next,
+		"SUMMARY:Курс лекций",
+		"RRULE:FREQ=WEEKLY;COUNT=10",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"DESCRIPTION:без заголовка",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	store := database.NewMemoryStore()
+	srv, err := server.New(testConfig(), server.WithStore(store))
+	require.NoError(t, err)
+
+	var report server.ImportReport
+	res := doJSON(t, srv.Handler, http.MethodPost, "/api/import/ics?dry_run=true", calendar, nil, &report)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Imported)
+	require.Len(t, report.Skipped, 2)
+	assert.Equal(t, "ends@example", report.Skipped[0].UID)
+	assert.Contains(t, report.Skipped[0].Reason, "COUNT")
+	assert.Equal(t, 13, report.Skipped[1].Line)
+
+	tasks, err := store.All(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "пробный запуск не меняет хранилище")
+
+	report = server.ImportReport{}
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/import/ics", calendar, nil, &report)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, report.Imported)
+	require.Len(t, report.Tasks, 1)
+	assert.NotEmpty(t, report.Tasks[0].ID)
+
+	task, err := store.Get(context.Background(), report.Tasks[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, database.Task{ID: task.ID, Date: next, Title: "Полить цветы", Repeat: "d 3"}, task)
+
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/import/ics", "не календарь", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/import/ics?dry_run=может", calendar, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+// failingImportStore - хранилище, в котором не удается импорт.
+type failingImportStore struct {
+	database.TaskStore
+}
+
+func (failingImportStore) Import(context.Context, []database.Task, database.ConflictPolicy) ([]database.ImportResult, error) {
+	return nil, errors.New("диск заполнен")
+}
+
+func TestImportICSAddsAllOrNothing(t *testing.T) {
+	next := time.Now().AddDate(0, 0, 1).Format("20060102")
+	calendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:" + next + "\r\nSUMMARY:Первая\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART;VALUE=DATE:" + next + "\r\nSUMMARY:Вторая\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	store := database.NewMemoryStore()
+	srv, err := server.New(testConfig(), server.WithStore(failingImportStore{store}))
+	require.NoError(t, err)
+
+	res := doJSON(t, srv.Handler, http.MethodPost, "/api/import/ics", calendar, nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode)
+	tasks, err := store.All(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	report, err := server.ImportICS(context.Background(), store, strings.NewReader(calendar), time.Now(), false)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Imported)
+	assert.NotEqual(t, report.Tasks[0].ID, report.Tasks[1].ID)
+}
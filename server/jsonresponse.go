@@ -0,0 +1,26 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// errorResponse - тело ответа с описанием ошибки.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON сериализует v в JSON и отправляет клиенту с указанным статусом.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Ошибка записи JSON-ответа", "error", err)
+	}
+}
+
+// writeError отправляет клиенту ошибку в виде {"error": "..."}.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"3code/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// metricsCollectTimeout - сколько ждать подсчета задач при запросе метрик.
+const metricsCollectTimeout = 2 * time.Second
+
+// serverMetrics - метрики HTTP-сервера, которые обновляются по ходу работы.
+type serverMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.Gauge
+	shutdown *metrics.Gauge
+}
+
+// registerMetrics создает реестр метрик сервера, базы данных и задач. У каждого сервера свой реестр.
+func (s *Server) registerMetrics() {
+	reg := metrics.NewRegistry()
+	s.registry = reg
+	s.metrics = serverMetrics{
+		requests: reg.NewCounterVec("todo_http_requests_total", "Количество обработанных HTTP-запросов.", "method", "route", "status"),
+		duration: reg.NewHistogramVec("todo_http_request_duration_seconds", "Время обработки HTTP-запросов в секундах.", metrics.DefBuckets, "method", "route"),
+		inFlight: reg.NewGauge("todo_http_requests_in_flight", "Количество запросов, обрабатываемых прямо сейчас."),
+		shutdown: reg.NewGauge("todo_shutdown_duration_seconds", "Длительность последней корректной остановки сервера в секундах."),
+	}
+
+	reg.NewFunc("todo_tasks", "Количество задач по типу правила повторения (none - разовые).", metrics.TypeGauge, []string{"repeat"}, s.collectTaskCounts)
+
+	if s.db == nil {
+		return
+	}
+	dbStat := func(name, help string, typ metrics.Type, value func(st sql.DBStats) float64) {
+		reg.NewFunc(name, help, typ, nil, func() []metrics.Sample {
+			return []metrics.Sample{{Value: value(s.db.Stats())}}
+		})
+	}
+	dbStat("todo_db_max_open_connections", "Максимальное количество открытых соединений с базой данных.", metrics.TypeGauge,
+		func(st sql.DBStats) float64 { return float64(st.MaxOpenConnections) })
+	dbStat("todo_db_open_connections", "Количество открытых соединений с базой данных.", metrics.TypeGauge,
+		func(st sql.DBStats) float64 { return float64(st.OpenConnections) })
+	dbStat("todo_db_in_use_connections", "Количество соединений, занятых запросами.", metrics.TypeGauge,
+		func(st sql.DBStats) float64 { return float64(st.InUse) })
+	dbStat("todo_db_idle_connections", "Количество простаивающих соединений.", metrics.TypeGauge,
+		func(st sql.DBStats) float64 { return float64(st.Idle) })
+	dbStat("todo_db_wait_count_total", "Сколько раз запросы ждали свободного соединения.", metrics.TypeCounter,
+		func(st sql.DBStats) float64 { return float64(st.WaitCount) })
+	dbStat("todo_db_wait_duration_seconds_total", "Суммарное время ожидания свободного соединения в секундах.", metrics.TypeCounter,
+		func(st sql.DBStats) float64 { return st.WaitDuration.Seconds() })
+	dbStat("todo_db_max_idle_closed_total", "Сколько соединений закрыто из-за ограничения на простаивающие.", metrics.TypeCounter,
+		func(st sql.DBStats) float64 { return float64(st.MaxIdleClosed) })
+	dbStat("todo_db_max_lifetime_closed_total", "Сколько соединений закрыто из-за ограничения времени жизни.", metrics.TypeCounter,
+		func(st sql.DBStats) float64 { return float64(st.MaxLifetimeClosed) })
+}
+
+// collectTaskCounts считает задачи по типу правила повторения. При ошибке метрика просто не выводится.
+func (s *Server) collectTaskCounts() []metrics.Sample {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsCollectTimeout)
+	defer cancel()
+
+	counts, err := s.store.CountByRepeat(ctx)
+	if err != nil {
+		slog.Error("Ошибка подсчета задач для метрик", "error", err)
+		return nil
+	}
+
+	samples := make([]metrics.Sample, 0, len(counts))
+	for kind, n := range counts {
+		if kind == "" {
+			kind = "none"
+		}
+		samples = append(samples, metrics.Sample{LabelValues: []string{kind}, Value: float64(n)})
+	}
+	return samples
+}
+
+// metricsMiddleware считает запросы и время их обработки по шаблону маршрута chi,
+// чтобы запросы всех файлов фронтенда попадали в одну серию /*, а не плодили по серии на файл.
+func (s *Server) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		s.metrics.inFlight.Inc()
+		defer s.metrics.inFlight.Dec()
+
+		rec, ok := w.(*responseRecorder)
+		if !ok {
+			rec = &responseRecorder{ResponseWriter: w}
+		}
+		next.ServeHTTP(rec, r)
+
+		// Шаблон становится известен только после того, как chi выбрал маршрут
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		s.metrics.requests.Inc(r.Method, route, strconv.Itoa(rec.Status()))
+		s.metrics.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
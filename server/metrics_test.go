@@ -0,0 +1,61 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	db, err := database.OpenDatabase(":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, database.Migrate(db))
+
+	srv, err := server.New(testConfig(), server.WithStore(database.NewSQLiteStore(db)), server.WithDB(db))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	doJSON(t, h, http.MethodPost, "/api/task", `{"date":"20240101","title":"Зарядка","repeat":"d 1"}`, nil, nil)
+	doJSON(t, h, http.MethodGet, "/api/task?id=1", "", nil, nil)
+	doJSON(t, h, http.MethodGet, "/api/task?id=2", "", nil, nil)
+
+	res := doJSON(t, h, http.MethodGet, "/metrics", "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`todo_http_requests_total{method="POST",route="/api/task",status="201"} 1`,
+		`todo_http_requests_total{method="GET",route="/api/task",status="200"} 1`,
+		`todo_http_requests_total{method="GET",route="/api/task",status="404"} 1`,
+		`todo_http_request_duration_seconds_count{method="GET",route="/api/task"} 2`,
+		`todo_http_requests_in_flight 1`, // Сам запрос /metrics
+		`todo_db_max_open_connections 1`,
+		`todo_tasks{repeat="d"} 1`,
+	} {
+		assert.Contains(t, string(body), line+"\n")
+	}
+}
+
+func TestMetricsRequireAuth(t *testing.T) {
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	res := doJSON(t, srv.Handler, http.MethodGet, "/metrics", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "при заданном пароле метрики без токена недоступны")
+
+	res = doJSON(t, srv.Handler, http.MethodPost, "/api/signin", `{"password":"секрет"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = doJSON(t, srv.Handler, http.MethodGet, "/metrics", "", res.Cookies(), nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// requestIDHeader - заголовок, в котором клиент или прокси передает идентификатор запроса и в котором сервер его возвращает.
+const requestIDHeader = "X-Request-ID"
+
+// contextKey - тип ключей контекста пакета, чтобы они не пересекались с ключами других пакетов.
+type contextKey string
+
+// keyRequestID - ключ контекста, под которым хранится идентификатор запроса.
+const keyRequestID contextKey = "requestID"
+
+// maxRequestIDLen - идентификаторы длиннее этого не принимаются от клиента, вместо них выдается новый.
+const maxRequestIDLen = 128
+
+// RequestIDFromContext возвращает идентификатор запроса, сохраненный middleware RequestID, или пустую строку.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(keyRequestID).(string)
+	return id
+}
+
+// RequestID берет идентификатор запроса из заголовка X-Request-ID или создает новый,
+// сохраняет его в контексте запроса и возвращает клиенту в том же заголовке.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyRequestID, id)))
+	})
+}
+
+// RequestLogger пишет в лог метод, путь, статус, размер ответа и время обработки каждого запроса.
+// Ответы 5xx логируются с уровнем ERROR, 4xx - с уровнем WARN.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		switch {
+		case rec.Status() >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rec.Status() >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(r.Context(), level, "HTTP-запрос",
+			"request_id", RequestIDFromContext(r.Context()),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.Status(),
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
+
+// Recoverer перехватывает панику в обработчике, пишет ее в лог вместе со стеком
+// и отвечает клиенту 500 в формате JSON, если ответ еще не начат.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := w.(*responseRecorder)
+		if !ok {
+			rec = &responseRecorder{ResponseWriter: w}
+		}
+
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// http.ErrAbortHandler - штатный способ прервать ответ, net/http обработает его сам
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			slog.Error("Паника при обработке запроса",
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(p),
+				"stack", string(debug.Stack()),
+			)
+			if !rec.wroteHeader {
+				writeError(rec, http.StatusInternalServerError, "внутренняя ошибка сервера")
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// responseRecorder запоминает статус и размер ответа для лога.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Status возвращает отправленный статус. Если обработчик ничего не записал, net/http ответит 200.
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter (Flush, дедлайны и т.п.).
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// validRequestID проверяет идентификатор от клиента: непустой, не слишком длинный и только из видимых ASCII-символов,
+// чтобы его можно было без опаски писать в лог и заголовки.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID создает случайный идентификатор запроса.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand на поддерживаемых системах не возвращает ошибок, но без идентификатора запрос все равно обработаем
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog перенаправляет логгер по умолчанию в буфер на время теста.
+func captureLog(t *testing.T) *bytes.Buffer {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	return &buf
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	h := server.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = server.RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "abc-123", seen, "идентификатор клиента передается дальше")
+	assert.Equal(t, "abc-123", rec.Header().Get("X-Request-ID"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "с пробелом")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Len(t, seen, 32, "некорректный идентификатор заменяется новым")
+	assert.Equal(t, seen, rec.Header().Get("X-Request-ID"))
+}
+
+func TestRecovererAndRequestLogger(t *testing.T) {
+	buf := captureLog(t)
+
+	h := server.RequestID(server.RequestLogger(server.Recoverer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("что-то сломалось")
+	}))))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error":"внутренняя ошибка сервера"}`, rec.Body.String())
+
+	dec := json.NewDecoder(buf)
+	var panicLog, accessLog map[string]any
+	require.NoError(t, dec.Decode(&panicLog))
+	require.NoError(t, dec.Decode(&accessLog))
+
+	assert.Equal(t, "что-то сломалось", panicLog["panic"])
+	assert.Contains(t, panicLog["stack"], "middleware_test.go")
+
+	assert.Equal(t, "req-1", accessLog["request_id"])
+	assert.Equal(t, "/api/tasks", accessLog["path"])
+	assert.EqualValues(t, http.StatusInternalServerError, accessLog["status"])
+	assert.EqualValues(t, rec.Body.Len(), accessLog["bytes"])
+}
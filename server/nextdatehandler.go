@@ -0,0 +1,36 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"3code/rules"
+)
+
+// nextDateHandler обрабатывает GET /api/nextdate?now=&date=&repeat= и возвращает следующую дату задачи в виде текста.
+func nextDateHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	// Параметр now необязательный, по умолчанию берем текущую дату
+	if nowParam := r.FormValue("now"); nowParam != "" {
+		parsed, err := time.Parse(rules.DateFormat, nowParam)
+		if err != nil {
+			http.Error(w, "некорректный параметр now: ожидается дата в формате 20060102", http.StatusBadRequest)
+			return
+		}
+		now = parsed
+	}
+
+	next, err := rules.NextDate(now, r.FormValue("date"), r.FormValue("repeat"))
+	if err != nil {
+		slog.Debug("Ошибка вычисления следующей даты", "date", r.FormValue("date"), "repeat", r.FormValue("repeat"), "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(next)); err != nil {
+		slog.Error("Ошибка записи ответа", "error", err)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"3code/config"
+	"3code/logger"
+)
+
+// WithReload задает функцию, которая заново читает настройки при получении SIGHUP. Кроме настроек сервера
+// из них применяется уровень логирования. current - настройки, с которыми запущен процесс: с ними сравниваются
+// перечитанные, чтобы записать в лог только изменения. Без этой опции SIGHUP только пишется в лог.
+func WithReload(current config.Config, load func() (config.Config, error)) Option {
+	return func(s *Server) {
+		s.loaded = current
+		s.reload = load
+	}
+}
+
+// config возвращает текущие настройки сервера.
+func (s *Server) config() config.Server {
+	return *s.cfg.Load()
+}
+
+// serveHTTP передает запрос текущему маршрутизатору. Маршрутизатор заменяется целиком при перезагрузке настроек,
+// поэтому запросы, которые уже обрабатываются, дорабатывают со старыми настройками.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.Load().(http.Handler).ServeHTTP(w, r)
+}
+
+// Reload заново читает настройки функцией из WithReload и применяет их без остановки сервера и обрыва соединений.
+// Таймауты чтения и записи, директория фронтенда, лимит списка задач, параметры входа и уровень логирования меняются сразу.
+// Порт, таймаут простоя, таймаут остановки, пауза перед остановкой и настройки TLS применяются только после перезапуска.
+// Если новые настройки отклонены, не применяется ничего, в том числе уровень логирования.
+func (s *Server) Reload() error {
+	if s.reload == nil {
+		return errors.New("метод Reload: перезагрузка настроек не настроена, используйте WithReload")
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	loaded, err := s.reload()
+	if err != nil {
+		return fmt.Errorf("метод Reload: %w", err)
+	}
+	next := loaded.Server
+	if err := checkAuthConfig(next); err != nil {
+		return fmt.Errorf("метод Reload: %w", err)
+	}
+
+	prev := s.config()
+	restartOnly := []struct {
+		name    string
+		changed bool
+	}{
+		{"TODO_PORT_7540", next.Port != prev.Port},
+		{"SERVER_IDLE_TIME", next.IdleTimeout != prev.IdleTimeout},
+		{"CTX_TIMEOUT", next.ShutdownTimeout != prev.ShutdownTimeout},
+		{"TODO_DRAIN_DELAY", next.DrainDelay != prev.DrainDelay},
+		{"TODO_TLS_*", next.TLSCertFile != prev.TLSCertFile || next.TLSKeyFile != prev.TLSKeyFile ||
+			next.TLSMinVersion != prev.TLSMinVersion || next.TLSCiphers != prev.TLSCiphers ||
+			next.TLSDev != prev.TLSDev || next.TLSDevDir != prev.TLSDevDir},
+		{"TODO_HTTP2", next.HTTP2 != prev.HTTP2},
+		{"TODO_HTTP_REDIRECT_PORT", next.RedirectPort != prev.RedirectPort},
+	}
+	for _, o := range restartOnly {
+		if o.changed {
+			slog.Warn("Настройка изменится только после перезапуска сервера", "name", o.name)
+		}
+	}
+	next.Port, next.IdleTimeout, next.ShutdownTimeout, next.DrainDelay = prev.Port, prev.IdleTimeout, prev.ShutdownTimeout, prev.DrainDelay
+	next.TLSCertFile, next.TLSKeyFile, next.TLSMinVersion, next.TLSCiphers = prev.TLSCertFile, prev.TLSKeyFile, prev.TLSMinVersion, prev.TLSCiphers
+	next.TLSDev, next.TLSDevDir, next.HTTP2, next.RedirectPort = prev.TLSDev, prev.TLSDevDir, prev.HTTP2, prev.RedirectPort
+
+	// Встроенные файлы фронтенда от директории не зависят
+	if s.frontend == nil {
+		if err := checkDir(next.FrontendDir); err != nil {
+			slog.Warn("Директория фронтенда недоступна", "dir", next.FrontendDir, "error", err)
+		}
+	}
+
+	s.cfg.Store(&next)
+	s.handler.Store(s.routes())
+	logger.SetLevel(loaded.Logger.Level)
+	loaded.LogChanges(s.loaded)
+	s.loaded = loaded
+	slog.Info("Настройки сервера перезагружены")
+	return nil
+}
+
+// deadlines выставляет дедлайны чтения и записи для каждого запроса по текущим настройкам.
+// Таймауты http.Server задаются один раз при запуске, а дедлайны через ResponseController
+// позволяют менять их на лету при перезагрузке настроек.
+func (s *Server) deadlines(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.config()
+		now := time.Now()
+		rc := http.NewResponseController(w)
+
+		// Не все ResponseWriter поддерживают дедлайны (например, в тестах), в этом случае остаются таймауты http.Server
+		if err := rc.SetReadDeadline(now.Add(cfg.ReadTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Debug("Не удалось установить дедлайн чтения", "error", err)
+		}
+		if err := rc.SetWriteDeadline(now.Add(cfg.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.Debug("Не удалось установить дедлайн записи", "error", err)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"3code/config"
+	"3code/database"
+	"3code/logger"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadAppliesConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("привет"), 0644))
+
+	prevLevel := logger.Level()
+	t.Cleanup(func() { logger.SetLevel(prevLevel) })
+	logger.SetLevel(slog.LevelInfo)
+
+	cfg := testConfig()
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()), server.WithReload(config.Config{Server: cfg}, func() (config.Config, error) {
+		next := cfg
+		next.FrontendDir = dir
+		next.Port = "1" // Порт меняется только после перезапуска
+		return config.Config{Server: next, Logger: config.Logger{Level: slog.LevelWarn}}, nil
+	}))
+	require.NoError(t, err)
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hello.txt", nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusNotFound, get())
+
+	require.NoError(t, srv.Reload())
+	assert.Equal(t, http.StatusOK, get(), "директория фронтенда меняется без перезапуска")
+	assert.Equal(t, ":0", srv.Addr)
+	assert.Equal(t, slog.LevelWarn, logger.Level(), "уровень логирования меняется после принятия настроек")
+
+	withoutReload, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	assert.Error(t, withoutReload.Reload())
+}
+
+// freePort возвращает порт, который только что был свободен.
+func freePort(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+func TestSecondSignalForcesShutdown(t *testing.T) {
+	cfg := testConfig()
+	cfg.Port = freePort(t)
+	cfg.ReadTimeout = time.Minute
+	cfg.ShutdownTimeout = time.Minute
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(context.Background()) }()
+
+	// Недописанный запрос держит соединение активным, поэтому корректная остановка будет ждать его до таймаута
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", "127.0.0.1:"+cfg.Port)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET /healthz HTTP/1.1\r\nHost: localhost\r\n")
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	srv.StopChan <- syscall.SIGTERM
+	select {
+	case <-done:
+		t.Fatal("сервер не должен остановиться, пока соединение активно")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	srv.StopChan <- syscall.SIGINT
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("сервер не остановился после повторного сигнала")
+	}
+}
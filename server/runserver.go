@@ -2,17 +2,27 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"3code/config"
+	"3code/database"
+	"3code/lifecycle"
+	"3code/metrics"
+
 	"github.com/go-chi/chi/v5"
-	"github.com/joho/godotenv"
 )
 
 type Server struct {
@@ -20,148 +30,364 @@ type Server struct {
 	*http.Server
 	// Объявляем канал StopChan, который будет использоваться для передачи сигналов ОС
 	StopChan chan os.Signal
-}
 
-// envLink string = "02_env/server.env"
-const envLink string = "./02_env/server.env"
+	// Настройки и зависимости конкретного экземпляра, чтобы в одном процессе могли работать несколько серверов
+	// Настройки меняются при перезагрузке по SIGHUP, поэтому читать их нужно через config()
+	cfg   atomic.Pointer[config.Server]
+	store database.TaskStore
+	db    *sql.DB // Необязательна, нужна только для проверки готовности и метрик
+	// Файлы фронтенда, встроенные в бинарный файл. nil - файлы читаются из директории FrontendDir
+	frontend fs.FS
+	// Резервные копии базы данных. nil - административные маршруты резервного копирования отключены
+	backups *database.Backups
 
-var (
-	frontEnd   string
-	serverPort string
+	registry *metrics.Registry
+	metrics  serverMetrics
 
-	readTime       time.Duration
-	writeTime      time.Duration
-	idleTime       time.Duration
-	contextTimeout time.Duration
-	timeUnit       string
-)
+	// Становится true, как только начинается остановка: /readyz после этого отвечает 503
+	draining atomic.Bool
+	// true, пока сервер принимает соединения
+	serving atomic.Bool
+	// Слушающий сокет (net.Listener), его можно передать новому процессу
+	listener atomic.Value
+
+	// Сервер перенаправления HTTP на HTTPS и его сокет. nil, если перенаправление не настроено
+	redirect         *http.Server
+	redirectListener atomic.Value
+
+	lifecycle *lifecycle.Manager
+
+	// Текущий маршрутизатор (http.Handler) и функция перечитывания настроек для SIGHUP.
+	// loaded - последние принятые настройки, с ними сравниваются перечитанные; перезагрузки идут по одной
+	handler  atomic.Value
+	reload   func() (config.Config, error)
+	reloadMu sync.Mutex
+	loaded   config.Config
+}
+
+// Option задает зависимость сервера при создании через New.
+type Option func(*Server)
 
-func init() {
-	// Загружаем конфигурацию
-	log.Println("Загрузка конфигурации из .env файла...")
-	loadEnvConfig()
+// WithStore задает хранилище задач, с которым работает API.
+func WithStore(store database.TaskStore) Option {
+	return func(s *Server) {
+		s.store = store
+	}
 }
 
-// Загружает настройки сервера из файла .env и возвращает ошибку, если не удалось.
-func loadEnvConfig() {
-	if err := godotenv.Load(envLink); err != nil {
-		log.Printf("Ошибка при загрузке .env файла: %v", err)
-		log.Fatal("Фатальная остановка при попытке загрузке конфигурации сервера из .env файла")
+// WithLifecycle задает менеджер остановки. Сервер добавляет в него свои шаги при создании,
+// поэтому шаги, добавленные в менеджер после New, выполняются после остановки HTTP.
+// Без этой опции у сервера свой менеджер с таймаутом шага ShutdownTimeout.
+func WithLifecycle(m *lifecycle.Manager) Option {
+	return func(s *Server) {
+		s.lifecycle = m
 	}
+}
 
-	frontEnd = os.Getenv("TODO_FRONTEND_DIR")
-	serverPort = os.Getenv("TODO_PORT_7540")
-	log.Printf("Загруженные переменные: frontEnd=%s, serverPort=%s", frontEnd, serverPort)
+// New создает сервер с настройками cfg и зависимостями opts. Сервер не запускается до вызова Run.
+func New(cfg config.Server, opts ...Option) (*Server, error) {
+	srv := &Server{
+		// Запас в буфере, чтобы повторный сигнал не потерялся, пока обрабатывается первый
+		StopChan: make(chan os.Signal, 2),
+	}
+	srv.cfg.Store(&cfg)
+	for _, opt := range opts {
+		opt(srv)
+	}
 
-	readTime = getDurationFromEnv("SERVER_READ_TIME", 10)
-	writeTime = getDurationFromEnv("SERVER_WRITE_TIME", 10)
-	idleTime = getDurationFromEnv("SERVER_IDLE_TIME", 120)
-	contextTimeout = getDurationFromEnv("CTX_TIMEOUT", 5)
-	timeUnit = os.Getenv("TIME_UNIT")
+	if srv.store == nil {
+		return nil, errors.New("функция New: не задано хранилище задач, используйте WithStore")
+	}
+	if err := checkAuthConfig(cfg); err != nil {
+		return nil, fmt.Errorf("функция New: %w", err)
+	}
+	srv.registerMetrics()
 
-	log.Println("Файл .env с конфигурацией сервера успешно загружен.")
-}
+	if cfg.Password == "" {
+		slog.Warn("Пароль не задан, аутентификация отключена")
+	}
+	if srv.frontend != nil {
+		slog.Info("Используются файлы фронтенда, встроенные в бинарный файл")
+	}
 
-func getDurationFromEnv(varName string, defaultValue int) time.Duration {
-	value := os.Getenv(varName)
-	if value == "" {
-		log.Printf("Переменная окружения %s не установлена. Используем значение по умолчанию: %d", varName, defaultValue)
-		return time.Duration(defaultValue) * time.Second
+	srv.handler.Store(srv.routes())
+	srv.Server = &http.Server{
+		Addr:         fmt.Sprintf(":%s", cfg.Port),
+		Handler:      http.HandlerFunc(srv.serveHTTP),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
 	}
 
-	duration, err := time.ParseDuration(value + timeUnit)
-	if err != nil {
-		log.Fatalf("Ошибка при парсинге переменной окружения %s: %v", varName, err)
+	if cfg.TLSEnabled() {
+		tlsCfg, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("функция New: %w", err)
+		}
+		srv.Server.TLSConfig = tlsCfg
+		// HTTP/2 включается в ServeTLS автоматически, отключить его можно только пустой картой TLSNextProto
+		if !cfg.HTTP2 {
+			srv.Server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+	}
+	if cfg.RedirectPort != "" {
+		srv.redirect = &http.Server{
+			Addr:         fmt.Sprintf(":%s", cfg.RedirectPort),
+			Handler:      redirectToHTTPS(cfg.Port),
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		}
 	}
 
-	return duration
-}
+	if srv.lifecycle == nil {
+		srv.lifecycle = lifecycle.New(cfg.ShutdownTimeout)
+	}
+	// Запас в секунду сверху, чтобы пауза не упиралась в таймаут собственного шага
+	srv.lifecycle.Add("снятие готовности", cfg.DrainDelay+time.Second, srv.markNotReady)
+	srv.lifecycle.Add("завершение HTTP-запросов", cfg.ShutdownTimeout, srv.drainHTTP)
+	if srv.redirect != nil {
+		srv.lifecycle.Add("остановка перенаправления на HTTPS", 0, srv.redirect.Shutdown)
+	}
 
-func RunServer() {
-	// Создаем экземпляр сервера
-	srv := createServer()
+	return srv, nil
+}
 
+// Run запускает сервер и блокируется до его остановки.
+// Остановка начинается при отмене ctx или при получении сигнала в StopChan (SIGINT, SIGTERM)
+// и выполняется менеджером остановки: сначала шаги сервера, затем шаги, добавленные вызывающим кодом.
+// Возвращает ошибку запуска сервера вместе с ошибками шагов остановки.
+func (s *Server) Run(ctx context.Context) error {
 	// Добавляем обработку сигналов
-	handleSignals(srv)
+	handleSignals(s)
+	defer signal.Stop(s.StopChan)
 
-	// Создаем WaitGroup для ожидания завершения работы серверной горутины
-	// У нас тут две горутины: одна для запуска сервера и одна для обработки остановки, поэтому 2
-	var wg sync.WaitGroup
-	wg.Add(2)
+	// Канал закрывается, когда сервер перестал работать, в том числе если он так и не смог запуститься
+	serverDone := make(chan struct{})
+	var startErr error
 
 	go func() {
-		defer wg.Done()
-		log.Println("Запускаем сервер...")
-		if err := startServer(srv); err != nil {
-			log.Printf("Ошибка запуска сервера: %v", err)
+		defer close(serverDone)
+		slog.Info("Запускаем сервер")
+		if startErr = startServer(s); startErr != nil {
+			slog.Error("Ошибка запуска сервера", "error", startErr)
 		}
 	}()
 
-	// Обработка остановки сервера
-	go func() {
-		defer wg.Done()
-		log.Println("Ожидание сигнала остановки сервера...")
-		<-srv.StopChan
-		log.Println("Получен сигнал остановки сервера")
-
-		ctx, cancel := context.WithTimeout(context.Background(), contextTimeout*time.Second)
-		defer cancel()
+	if s.redirect != nil {
+		go s.serveRedirect()
+	}
 
-		if err := ServerTimeout(ctx, srv.Server); err != nil {
-			log.Printf("Ошибка при завершении работы сервера: %v", err)
+	slog.Debug("Ожидание сигнала остановки сервера")
+wait:
+	for {
+		select {
+		case sig := <-s.StopChan:
+			if sig == syscall.SIGHUP {
+				slog.Info("Получен сигнал перезагрузки настроек")
+				if err := s.Reload(); err != nil {
+					slog.Error("Настройки не перезагружены, сервер работает со старыми", "error", err)
+				}
+				continue
+			}
+			if isHandoffSignal(sig) {
+				slog.Info("Получен сигнал перезапуска без простоя")
+				if err := s.handoff(); err != nil {
+					slog.Error("Сокет не передан новому процессу, сервер продолжает работу", "error", err)
+					continue
+				}
+				slog.Info("Новый процесс принимает соединения, завершаем текущие запросы и останавливаемся")
+				break wait
+			}
+			slog.Info("Получен сигнал остановки сервера", "signal", sig)
+		case <-ctx.Done():
+			slog.Info("Контекст сервера отменен, начинаем остановку")
+		case <-serverDone:
+			// Сервер не запустился. Остальные шаги остановки (база данных, логи) все равно нужно выполнить
 		}
-	}()
+		break wait
+	}
 
-	log.Println("Ожидание остановки сервера...")
-	wg.Wait()
-	log.Println("Сервер остановлен.")
+	// Повторный сигнал остановки во время корректной остановки закрывает все соединения сразу
+	stopForce := make(chan struct{})
+	go s.forceCloseOnSignal(stopForce)
+	defer close(stopForce)
+
+	shutdownErr := s.lifecycle.Shutdown(context.Background())
+	<-serverDone
+	slog.Info("Сервер остановлен")
+
+	return errors.Join(startErr, shutdownErr)
 }
 
-// Создаёт экземпляр сервера, настраивает маршруты для обслуживания статики и задаёт параметры подключения (порт и таймауты)
-func createServer() *Server {
-	r := chi.NewRouter()
-	r.Handle("/*", http.FileServer(http.Dir(frontEnd)))
+// forceCloseOnSignal ждет повторного SIGINT или SIGTERM до закрытия done и немедленно закрывает все соединения
+// основного сервера и перенаправления на HTTPS, не дожидаясь завершения текущих запросов.
+func (s *Server) forceCloseOnSignal(done <-chan struct{}) {
+	for {
+		select {
+		case sig := <-s.StopChan:
+			if sig == syscall.SIGHUP || isHandoffSignal(sig) {
+				slog.Info("Сигнал во время остановки пропущен", "signal", sig)
+				continue
+			}
+			slog.Warn("Повторный сигнал остановки, закрываем все соединения немедленно", "signal", sig)
+			if err := s.Server.Close(); err != nil {
+				slog.Error("Ошибка принудительного закрытия соединений", "error", err)
+			}
+			if s.redirect != nil {
+				if err := s.redirect.Close(); err != nil {
+					slog.Error("Ошибка принудительного закрытия соединений перенаправления на HTTPS", "error", err)
+				}
+			}
+			return
+		case <-done:
+			return
+		}
+	}
+}
 
-	if serverPort == "" {
-		log.Fatalf("Фатальная ошибка: Переменная %v не задана.", serverPort)
+// markNotReady помечает сервер как не готовый (/readyz отвечает 503) и дает балансировщику время убрать его из работы.
+// Слушатель при этом не закрывается и новые соединения принимаются, чтобы запросы, уже направленные к нам,
+// не получили отказ. Прием прекращает следующий шаг, drainHTTP.
+func (s *Server) markNotReady(ctx context.Context) error {
+	s.draining.Store(true)
+	if s.config().DrainDelay <= 0 || !s.serving.Load() {
+		return nil
 	}
 
-	srv := &Server{
-		Server: &http.Server{
-			Addr:         fmt.Sprintf(":%s", serverPort),
-			Handler:      r,
-			ReadTimeout:  readTime * time.Second,
-			WriteTimeout: writeTime * time.Second,
-			IdleTimeout:  idleTime * time.Second,
-		},
-		StopChan: make(chan os.Signal, 1),
+	slog.Info("Сервер помечен как не готовый, ждем перед остановкой", "delay", s.config().DrainDelay)
+	timer := time.NewTimer(s.config().DrainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drainHTTP закрывает слушатели и дожидается завершения запросов, которые уже обрабатываются.
+// Если запросы не завершились до таймаута шага, соединения закрываются принудительно: следующие шаги
+// (например, закрытие базы данных) начнутся сразу, и обработчики должны получить отмену контекста.
+func (s *Server) drainHTTP(ctx context.Context) error {
+	start := time.Now()
+	defer func() { s.metrics.shutdown.Set(time.Since(start).Seconds()) }()
+
+	err := ServerTimeout(ctx, s.Server)
+	if err != nil {
+		s.Server.Close()
 	}
+	return err
+}
+
+// routes настраивает маршруты API и обслуживания статики
+func (s *Server) routes() http.Handler {
+	r := chi.NewRouter()
+	// Идентификатор нужен логу запросов, а лог и метрики должны увидеть статус 500 после перехваченной паники
+	r.Use(RequestID, RequestLogger, s.metricsMiddleware, Recoverer, s.deadlines)
+	r.Get("/healthz", healthzHandler)
+	r.Get("/readyz", s.readyzHandler)
+	r.Get("/version", versionHandler)
+	r.Post("/api/signin", s.signinHandler)
+	// Календарь открывается и по ссылке с токеном, для подписки в календарных приложениях
+	r.With(s.feedAuthMiddleware).Get("/api/tasks.ics", icsHandler(s.store))
+
+	// Все остальные маршруты API доступны только с действительным токеном. Метрики тоже: по ним видны
+	// количество задач, состояние базы и обращения к API
+	r.Group(func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Method(http.MethodGet, "/metrics", s.registry.Handler())
+		r.Get("/api/nextdate", nextDateHandler)
+		r.Post("/api/task", addTaskHandler(s.store))
+		r.Get("/api/task", getTaskHandler(s.store))
+		r.Put("/api/task", updateTaskHandler(s.store))
+		r.Delete("/api/task", deleteTaskHandler(s.store))
+		r.Post("/api/task/done", doneTaskHandler(s.store))
+		r.Get("/api/tasks", listTasksHandler(s.store, s.config().TasksLimit))
+		r.Get("/api/feed", s.feedHandler)
+		r.Post("/api/import/ics", importICSHandler(s.store))
+		r.Get("/api/export", exportHandler(s.store))
+		r.Post("/api/import", importHandler(s.store))
+		if s.backups != nil {
+			r.With(s.adminMiddleware).Post("/api/admin/backup", createBackupHandler(s.backups))
+			r.With(s.adminMiddleware).Get("/api/admin/backups", listBackupsHandler(s.backups))
+		}
+	})
+	r.Handle("/*", newStaticHandler(s.frontendFS()))
 
-	return srv
+	return r
 }
 
-// Настраивает обработку сигналов ОС (SIGINT и SIGTERM), чтобы сервер мог корректно завершить свою работу.
+// Настраивает обработку сигналов ОС: SIGINT и SIGTERM корректно завершают работу сервера (повторный - принудительно),
+// SIGHUP перезагружает настройки, а там, где это поддерживается, SIGUSR2 передает слушающий сокет новому процессу.
 func handleSignals(srv *Server) {
-	signal.Notify(srv.StopChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(srv.StopChan, append([]os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}, handoffSignals...)...)
 }
 
 // Запускает HTTP сервер и логирует информацию о запуске.
+// Если процесс запущен предыдущим процессом при передаче сокета, сервер принимает соединения на унаследованном сокете.
 func startServer(srv *Server) error {
-	log.Printf("Запуск сервера на %s\n", srv.Addr)
+	ln, inherited, err := inheritedListener(listenFDEnv)
+	if err != nil {
+		return fmt.Errorf("функция startServer: ошибка получения унаследованного сокета: %w", err)
+	}
+	if inherited {
+		slog.Info("Запуск сервера на сокете, переданном предыдущим процессом", "addr", ln.Addr().String())
+	} else {
+		slog.Info("Запуск сервера", "addr", srv.Addr)
+		if ln, err = net.Listen("tcp", srv.Addr); err != nil {
+			return fmt.Errorf("функция startServer: фатальная ошибка при запуске сервера: %w", err)
+		}
+	}
+	srv.listener.Store(ln)
+
+	// Сокет уже принимает соединения, можно сообщить предыдущему процессу, что он может останавливаться
+	if inherited {
+		notifyReady()
+	}
 
-	// Попытка запустить сервер
-	err := srv.ListenAndServe()
+	// Попытка запустить сервер. Сертификат уже загружен в TLSConfig, поэтому пути к файлам не нужны
+	srv.serving.Store(true)
+	if srv.TLSConfig != nil {
+		slog.Info("Сервер принимает соединения по HTTPS", "http2", srv.config().HTTP2)
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	srv.serving.Store(false)
 	if err != nil {
 		if err == http.ErrServerClosed {
 			// Сервер корректно остановлен, можем просто вернуть nilку
-			log.Printf("Сервер успешно остановлен на %s", srv.Addr)
+			slog.Info("Сервер успешно остановлен", "addr", srv.Addr)
 			return nil
 		}
 		// Если произошла другая ошибка, логируем и возвращаем её
-		return fmt.Errorf("функция startServer: фатальная ошибка при запуске сервера: %w", err)
+		return fmt.Errorf("функция startServer: фатальная ошибка при работе сервера: %w", err)
 	}
 
-	// Этот лог не будет достигнут, так как ListenAndServe блокирует выполнение, поэтому он не нужен
-	log.Printf("Сервер успешно запущен на %s\n", srv.Addr)
+	// Этот лог не будет достигнут, так как Serve блокирует выполнение, поэтому он не нужен
+	slog.Info("Сервер успешно запущен", "addr", srv.Addr)
 	return nil // Возвращаем nil, если сервер успешен
 }
+
+// serveRedirect принимает HTTP-запросы на порту перенаправления и отправляет клиентов на HTTPS.
+// Ошибка здесь не останавливает основной сервер, поэтому только пишется в лог.
+func (s *Server) serveRedirect() {
+	ln, inherited, err := inheritedListener(redirectFDEnv)
+	if err != nil {
+		slog.Error("Ошибка получения унаследованного сокета перенаправления", "error", err)
+		return
+	}
+	if !inherited {
+		if ln, err = net.Listen("tcp", s.redirect.Addr); err != nil {
+			slog.Error("Не удалось запустить перенаправление на HTTPS", "addr", s.redirect.Addr, "error", err)
+			return
+		}
+	}
+	s.redirectListener.Store(ln)
+
+	slog.Info("Запуск перенаправления HTTP на HTTPS", "addr", ln.Addr().String())
+	if err := s.redirect.Serve(ln); err != nil && err != http.ErrServerClosed {
+		slog.Error("Ошибка перенаправления на HTTPS", "error", err)
+	}
+}
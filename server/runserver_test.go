@@ -1,42 +1,135 @@
-//go:build ignore
-
 package server_test
 
 import (
-	"3code/server"
+	"context"
+	"encoding/json"
 	"net/http"
-	"os"
-	"sync"
 	"syscall"
 	"testing"
 	"time"
 
+	"3code/config"
+	"3code/database"
+	"3code/server"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestServerShutdown(t *testing.T) {
-	var wg sync.WaitGroup
-	wg.Add(1)
-
-	srv := &server.Server{
-		Server:   &http.Server{Addr: ":7540"},
-		StopChan: make(chan os.Signal, 1),
+// testConfig возвращает настройки сервера для тестов. Порт 0 - любой свободный порт.
+func testConfig() config.Server {
+	return config.Server{
+		Port:            "0",
+		FrontendDir:     ".",
+		ReadTimeout:     time.Second,
+		WriteTimeout:    time.Second,
+		IdleTimeout:     time.Second,
+		ShutdownTimeout: time.Second,
+		TokenTTL:        time.Hour,
+		JWTSecret:       "тестовый секрет",
+		TasksLimit:      database.TasksLimit,
 	}
+}
 
+func TestServerShutdown(t *testing.T) {
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
 	go func() {
-		defer wg.Done()
-		server.ServerWithCancel(srv, &wg)
+		done <- srv.Run(context.Background())
 	}()
 
 	// Это будет имитировать задержку для сервера запуска
-	time.Sleep(1 * time.Second)
+	time.Sleep(100 * time.Millisecond)
 
 	// Отправляем сигнал остановки
 	srv.StopChan <- syscall.SIGINT
 
 	// Ждем завершения работы
-	wg.Wait()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("сервер не остановился после сигнала")
+	}
+}
+
+func TestServerStopsOnContextCancel(t *testing.T) {
+	// Два сервера с разными настройками в одном процессе: порт, пароль и лимит списка задач у каждого свои
+	firstCfg := testConfig()
+	firstCfg.Port = freePort(t)
+	firstCfg.Password = "первый"
+	secondCfg := testConfig()
+	secondCfg.Port = freePort(t)
+	secondCfg.TasksLimit = 1
+
+	store := database.NewMemoryStore()
+	for _, title := range []string{"Первая", "Вторая"} {
+		_, err := store.Add(context.Background(), database.Task{Date: "20240201", Title: title})
+		require.NoError(t, err)
+	}
+	first, err := server.New(firstCfg, server.WithStore(store))
+	require.NoError(t, err)
+	second, err := server.New(secondCfg, server.WithStore(store))
+	require.NoError(t, err)
+	assert.Equal(t, ":"+firstCfg.Port, first.Addr)
+	assert.Equal(t, ":"+secondCfg.Port, second.Addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 2)
+	go func() { done <- first.Run(ctx) }()
+	go func() { done <- second.Run(ctx) }()
+
+	get := func(port string) (*http.Response, error) {
+		return http.Get("http://127.0.0.1:" + port + "/api/tasks")
+	}
+	for _, port := range []string{firstCfg.Port, secondCfg.Port} {
+		require.Eventually(t, func() bool {
+			res, err := get(port)
+			if err == nil {
+				res.Body.Close()
+			}
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// Пароль первого сервера не включает аутентификацию у второго, а лимит второго не действует на первый
+	res, err := get(firstCfg.Port)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	var list struct {
+		Tasks []database.Task `json:"tasks"`
+	}
+	res, err = get(secondCfg.Port)
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, list.Tasks, 1)
+
+	res = doJSON(t, first.Handler, http.MethodPost, "/api/signin", `{"password":"первый"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	list.Tasks = nil
+	res = doJSON(t, first.Handler, http.MethodGet, "/api/tasks", "", res.Cookies(), &list)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, list.Tasks, 2)
+
+	cancel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("сервер не остановился после отмены контекста")
+		}
+	}
+}
 
-	// Убедитесь, что служба остановлена
-	assert.NotNil(t, srv.Server)
+func TestNewRequiresStore(t *testing.T) {
+	_, err := server.New(testConfig())
+	assert.Error(t, err)
 }
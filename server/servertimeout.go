@@ -3,19 +3,20 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
-// ServerTimeout завершает работу сервера с указанным тайм-аутом в секундах.
+// ServerTimeout корректно завершает работу сервера: перестает принимать соединения и ждет завершения
+// текущих запросов, но не дольше дедлайна ctx.
 func ServerTimeout(ctx context.Context, srv *http.Server) error {
 	// Извлекаем время дедлайна и проверяем, установлен ли он (с помощью переменной ok)
 	deadline, ok := ctx.Deadline()
 
 	if ok {
-		log.Printf("Начато завершение работы сервера с таймаутом до %s.\n", deadline)
+		slog.Info("Начато завершение работы сервера с таймаутом", "deadline", deadline)
 	} else {
-		log.Println("Начато завершение работы сервера без установленного таймаута.")
+		slog.Warn("Начато завершение работы сервера без установленного таймаута")
 	}
 
 	// Проходимся по завершению сервера
@@ -23,6 +24,6 @@ func ServerTimeout(ctx context.Context, srv *http.Server) error {
 		return fmt.Errorf("функция ServerTimeout: фатальная ошибка при завершении работы сервера: %w", err)
 	}
 
-	log.Println("Сервер успешно остановлен.")
+	slog.Info("Сервер успешно остановлен")
 	return nil // Возвращаем nil, если завершение прошло успешно
 }
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staticMaxAge - сколько браузер может использовать файл фронтенда без повторной проверки.
+// HTML-страницы проверяются при каждом запросе, чтобы после обновления фронтенда браузер сразу получил ссылки на новые файлы.
+const staticMaxAge = time.Hour
+
+// precompressed - расширения заранее сжатых копий файлов в порядке предпочтения
+var precompressed = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// WithFrontend задает файлы фронтенда, например встроенные в бинарный файл через embed.FS.
+// Без этой опции или с nil файлы читаются из директории TODO_FRONTEND_DIR.
+func WithFrontend(fsys fs.FS) Option {
+	return func(s *Server) {
+		s.frontend = fsys
+	}
+}
+
+// frontendFS возвращает файлы фронтенда: встроенные, если они заданы, иначе директорию из текущих настроек.
+func (s *Server) frontendFS() fs.FS {
+	if s.frontend != nil {
+		return s.frontend
+	}
+	return os.DirFS(s.config().FrontendDir)
+}
+
+// staticHandler раздает файлы фронтенда. В отличие от http.FileServer он отдает сжатые копии (.br, .gz),
+// выставляет ETag и Cache-Control, а на неизвестные пути без расширения отвечает index.html,
+// чтобы маршруты клиентского приложения открывались по прямой ссылке.
+type staticHandler struct {
+	fsys fs.FS
+	// Посчитанные ETag по имени файла (etagEntry), чтобы не читать файл при каждом запросе
+	etags sync.Map
+}
+
+type etagEntry struct {
+	modTime time.Time
+	size    int64
+	etag    string
+}
+
+func newStaticHandler(fsys fs.FS) *staticHandler {
+	return &staticHandler{fsys: fsys}
+}
+
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	info, err := fs.Stat(h.fsys, name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, "index.html")
+		info, err = fs.Stat(h.fsys, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) && isClientRoute(name) {
+		name = "index.html"
+		info, err = fs.Stat(h.fsys, name)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			http.NotFound(w, r)
+			return
+		}
+		slog.Error("Ошибка чтения файла фронтенда", "file", name, "error", err)
+		http.Error(w, "ошибка чтения файла", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.serveFile(w, r, name, info); err != nil {
+		slog.Error("Ошибка чтения файла фронтенда", "file", name, "error", err)
+		http.Error(w, "ошибка чтения файла", http.StatusInternalServerError)
+	}
+}
+
+// serveFile отдает файл name или его сжатую копию, если клиент ее принимает.
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) error {
+	served, encoding := name, ""
+	for _, p := range precompressed {
+		if !acceptsEncoding(r, p.encoding) {
+			continue
+		}
+		if ci, err := fs.Stat(h.fsys, name+p.ext); err == nil && !ci.IsDir() {
+			served, encoding, info = name+p.ext, p.encoding, ci
+			break
+		}
+	}
+
+	f, err := h.fsys.Open(served)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Файлы embed.FS и os.DirFS поддерживают Seek, остальные читаем в память
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+	etag, err := h.etag(served, info, content)
+	if err != nil {
+		return err
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Accept-Encoding")
+	header.Set("ETag", etag)
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+	if path.Ext(name) == ".html" {
+		header.Set("Cache-Control", "no-cache")
+	} else {
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(staticMaxAge.Seconds())))
+	}
+
+	// Тип содержимого ServeContent определяет по имени исходного файла, а не сжатой копии.
+	// Он же отвечает 304 на If-None-Match и обрабатывает Range
+	http.ServeContent(w, r, name, info.ModTime(), content)
+	return nil
+}
+
+// etag возвращает ETag файла по хешу его содержимого. У встроенных файлов нет времени изменения,
+// поэтому для них подходит только хеш. Посчитанное значение используется, пока не изменились время и размер файла.
+func (h *staticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if v, ok := h.etags.Load(name); ok {
+		entry := v.(etagEntry)
+		if entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+			return entry.etag, nil
+		}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := fmt.Sprintf(`"%x"`, hash.Sum(nil)[:16])
+	h.etags.Store(name, etagEntry{modTime: info.ModTime(), size: info.Size(), etag: etag})
+	return etag, nil
+}
+
+// isClientRoute сообщает, похож ли путь на маршрут клиентского приложения, а не на файл или метод API.
+func isClientRoute(name string) bool {
+	return path.Ext(name) == "" && name != "api" && !strings.HasPrefix(name, "api/")
+}
+
+// acceptsEncoding сообщает, принимает ли клиент ответ, сжатый способом encoding, по заголовку Accept-Encoding.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+		// q=0 означает явный отказ от этого способа сжатия
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticFiles(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":    {Data: []byte("<html>задачи</html>")},
+		"js/app.js":     {Data: []byte("console.log('app')")},
+		"js/app.js.br":  {Data: []byte("br-данные")},
+		"js/app.js.gz":  {Data: []byte("gzip-данные")},
+		"css/style.css": {Data: []byte("body{}")},
+	}
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()), server.WithFrontend(files))
+	require.NoError(t, err)
+
+	get := func(target string, header http.Header) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+	body := func(res *http.Response) string {
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("сжатые копии", func(t *testing.T) {
+		res := get("/js/app.js", http.Header{"Accept-Encoding": {"gzip, br"}})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "br", res.Header.Get("Content-Encoding"))
+		assert.Equal(t, "br-данные", body(res))
+		assert.Contains(t, res.Header.Get("Content-Type"), "javascript")
+		assert.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+
+		res = get("/js/app.js", http.Header{"Accept-Encoding": {"gzip, br;q=0"}})
+		assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		assert.Equal(t, "gzip-данные", body(res))
+
+		res = get("/js/app.js", nil)
+		assert.Empty(t, res.Header.Get("Content-Encoding"))
+		assert.Equal(t, "console.log('app')", body(res))
+	})
+
+	t.Run("кеширование", func(t *testing.T) {
+		res := get("/css/style.css", nil)
+		assert.Equal(t, "public, max-age=3600", res.Header.Get("Cache-Control"))
+		etag := res.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		res = get("/css/style.css", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, res.StatusCode)
+
+		// У сжатой копии свой ETag
+		res = get("/js/app.js", http.Header{"Accept-Encoding": {"br"}})
+		brTag := res.Header.Get("ETag")
+		res = get("/js/app.js", nil)
+		assert.NotEqual(t, brTag, res.Header.Get("ETag"))
+
+		res = get("/", nil)
+		assert.Equal(t, "no-cache", res.Header.Get("Cache-Control"))
+	})
+
+	t.Run("маршруты клиентского приложения", func(t *testing.T) {
+		for _, target := range []string{"/", "/tasks/42", "/settings"} {
+			res := get(target, nil)
+			assert.Equal(t, http.StatusOK, res.StatusCode, target)
+			assert.Equal(t, "<html>задачи</html>", body(res), target)
+		}
+		// Отсутствующие файлы и методы API не подменяются страницей приложения
+		for _, target := range []string{"/js/missing.js", "/api/unknown", "/../index.html.bak"} {
+			res := get(target, nil)
+			assert.Equal(t, http.StatusNotFound, res.StatusCode, target)
+		}
+	})
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"3code/database"
+	"3code/rules"
+)
+
+// tasksResponse - тело ответа GET /api/tasks.
+type tasksResponse struct {
+	Tasks []database.Task `json:"tasks"`
+}
+
+// idResponse - тело ответа POST /api/task.
+type idResponse struct {
+	ID string `json:"id"`
+}
+
+// emptyResponse - пустой JSON-объект {}, которым отвечаем на успешные изменения.
+type emptyResponse struct{}
+
+// addTaskHandler обрабатывает POST /api/task и добавляет новую задачу.
+func addTaskHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var task database.Task
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			writeError(w, http.StatusBadRequest, "ошибка десериализации JSON")
+			return
+		}
+
+		if err := validateTask(&task, time.Now()); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id, err := store.Add(r.Context(), task)
+		if err != nil {
+			slog.Error("Ошибка добавления задачи", "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось добавить задачу")
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, idResponse{ID: id})
+	}
+}
+
+// getTaskHandler обрабатывает GET /api/task?id= и возвращает задачу по идентификатору.
+func getTaskHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+
+		task, err := store.Get(r.Context(), id)
+		if err != nil {
+			writeTaskError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, task)
+	}
+}
+
+// updateTaskHandler обрабатывает PUT /api/task и обновляет задачу целиком.
+func updateTaskHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var task database.Task
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			writeError(w, http.StatusBadRequest, "ошибка десериализации JSON")
+			return
+		}
+
+		if _, err := strconv.ParseInt(task.ID, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "некорректный идентификатор задачи")
+			return
+		}
+
+		if err := validateTask(&task, time.Now()); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := store.Update(r.Context(), task); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, emptyResponse{})
+	}
+}
+
+// deleteTaskHandler обрабатывает DELETE /api/task?id= и удаляет задачу.
+func deleteTaskHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := store.Delete(r.Context(), id); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, emptyResponse{})
+	}
+}
+
+// doneTaskHandler обрабатывает POST /api/task/done?id= и отмечает задачу выполненной.
+func doneTaskHandler(store database.TaskStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := taskID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := store.MarkDone(r.Context(), id, time.Now()); err != nil {
+			writeTaskError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, emptyResponse{})
+	}
+}
+
+// searchDateFormat - формат, в котором пользователь вводит дату в строке поиска.
+const searchDateFormat = "02.01.2006"
+
+// listTasksHandler обрабатывает GET /api/tasks?search= и возвращает ближайшие задачи.
+// Возвращается не более limit задач. Если search похож на дату вида 02.01.2006, возвращаются задачи на эту дату,
+// иначе - задачи, в заголовке или комментарии которых встречается search.
+func listTasksHandler(store database.TaskStore, limit int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		search := strings.TrimSpace(r.FormValue("search"))
+
+		var tasks []database.Task
+		var err error
+		if search == "" {
+			tasks, err = store.List(r.Context(), limit)
+		} else if date, parseErr := time.Parse(searchDateFormat, search); parseErr == nil {
+			tasks, err = store.ListByDate(r.Context(), date.Format(rules.DateFormat), limit)
+		} else {
+			tasks, err = store.Search(r.Context(), search, limit)
+		}
+
+		if err != nil {
+			slog.Error("Ошибка получения списка задач", "search", search, "error", err)
+			writeError(w, http.StatusInternalServerError, "не удалось получить список задач")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tasksResponse{Tasks: tasks})
+	}
+}
+
+// validateTask проверяет поля задачи и приводит дату к ближайшей допустимой.
+// Пустая дата заменяется на сегодняшнюю. Если дата в прошлом, то для задачи без повторения
+// ставится сегодняшняя дата, а для повторяющейся - следующая дата по правилу.
+func validateTask(task *database.Task, now time.Time) error {
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return errors.New("не указан заголовок задачи")
+	}
+
+	today := now.Format(rules.DateFormat)
+	if task.Date == "" {
+		task.Date = today
+	}
+
+	date, err := time.Parse(rules.DateFormat, task.Date)
+	if err != nil {
+		return fmt.Errorf("дата %q указана в неверном формате, ожидается YYYYMMDD", task.Date)
+	}
+
+	// Правило проверяем всегда, чтобы не сохранить в базу то, что потом не получится разобрать
+	if task.Repeat != "" {
+		if _, err := rules.Parse(task.Repeat); err != nil {
+			return err
+		}
+	}
+
+	if date.Format(rules.DateFormat) < today {
+		if task.Repeat == "" {
+			task.Date = today
+		} else {
+			next, err := rules.NextDate(now, task.Date, task.Repeat)
+			if err != nil {
+				return err
+			}
+			task.Date = next
+		}
+	}
+
+	return nil
+}
+
+// taskID извлекает идентификатор задачи из параметра id и отвечает ошибкой, если он некорректен.
+func taskID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "не указан идентификатор задачи")
+		return "", false
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		writeError(w, http.StatusBadRequest, "некорректный идентификатор задачи")
+		return "", false
+	}
+	return id, true
+}
+
+// writeTaskError отправляет клиенту ошибку работы с задачей с подходящим статусом.
+// Некорректное правило повторения уже сохраненной задачи - ошибка данных, а не базы, поэтому на нее отвечаем 400.
+func writeTaskError(w http.ResponseWriter, err error) {
+	if errors.Is(err, database.ErrTaskNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var ruleErr *rules.RuleError
+	if errors.As(err, &ruleErr) {
+		writeError(w, http.StatusBadRequest, ruleErr.Error())
+		return
+	}
+	slog.Error("Ошибка работы с задачей", "error", err)
+	writeError(w, http.StatusInternalServerError, "ошибка работы с базой данных")
+}
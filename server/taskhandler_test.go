@@ -0,0 +1,179 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doJSON выполняет запрос к обработчику и разбирает JSON-ответ в out.
+func doJSON(t *testing.T, h http.Handler, method, target, body string, cookies []*http.Cookie, out any) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if out != nil {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(out))
+	}
+	return rec.Result()
+}
+
+func TestTaskAPI(t *testing.T) {
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	var created struct {
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	res := doJSON(t, h, http.MethodPost, "/api/task", `{"date":"20240101","title":"Зарядка","repeat":"d 1"}`, nil, &created)
+	require.Equal(t, http.StatusCreated, res.StatusCode, created.Error)
+
+	res = doJSON(t, h, http.MethodPost, "/api/task", `{"date":"2024-01-01","title":"Плохая дата"}`, nil, &created)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.NotEmpty(t, created.Error)
+
+	var list struct {
+		Tasks []database.Task `json:"tasks"`
+	}
+	doJSON(t, h, http.MethodGet, "/api/tasks?search=зарядка", "", nil, &list)
+	require.Len(t, list.Tasks, 1)
+	// Дата в прошлом для повторяющейся задачи переносится вперед
+	assert.Greater(t, list.Tasks[0].Date, "20240101")
+
+	res = doJSON(t, h, http.MethodDelete, "/api/task?id=42", "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestTaskValidation(t *testing.T) {
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	for name, body := range map[string]string{
+		"нет заголовка":    `{"date":"20240101","title":"  "}`,
+		"неверная дата":    `{"date":"01.01.2024","title":"Задача"}`,
+		"неверное правило": `{"date":"20240101","title":"Задача","repeat":"z 1"}`,
+		"правило без дней": `{"date":"20240101","title":"Задача","repeat":"w"}`,
+		"не JSON":          `{"title":`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out struct {
+				Error string `json:"error"`
+			}
+			res := doJSON(t, srv.Handler, http.MethodPost, "/api/task", body, nil, &out)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.NotEmpty(t, out.Error)
+		})
+	}
+}
+
+func TestTaskCRUD(t *testing.T) {
+	srv, err := server.New(testConfig(), server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	h := srv.Handler
+	today := time.Now().Format("20060102")
+
+	// Прошедшая дата разовой задачи заменяется сегодняшней, пустая - тоже
+	var created struct {
+		ID string `json:"id"`
+	}
+	res := doJSON(t, h, http.MethodPost, "/api/task", `{"date":"20200101","title":" Купить хлеб ","comment":"в булочной"}`, nil, &created)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var task database.Task
+	res = doJSON(t, h, http.MethodGet, "/api/task?id="+created.ID, "", nil, &task)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, database.Task{ID: created.ID, Date: today, Title: "Купить хлеб", Comment: "в булочной"}, task)
+
+	update := `{"id":"` + created.ID + `","date":"` + today + `","title":"Купить батон","repeat":"d 7"}`
+	res = doJSON(t, h, http.MethodPut, "/api/task", update, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = doJSON(t, h, http.MethodGet, "/api/task?id="+created.ID, "", nil, &task)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, database.Task{ID: created.ID, Date: today, Title: "Купить батон", Repeat: "d 7"}, task)
+
+	res = doJSON(t, h, http.MethodPut, "/api/task", `{"id":"999","date":"`+today+`","title":"Нет такой"}`, nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = doJSON(t, h, http.MethodPut, "/api/task", `{"id":"abc","title":"Плохой id"}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodDelete, "/api/task?id="+created.ID, "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = doJSON(t, h, http.MethodGet, "/api/task?id="+created.ID, "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = doJSON(t, h, http.MethodGet, "/api/task", "", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestTaskDone(t *testing.T) {
+	ctx := context.Background()
+	store := database.NewMemoryStore()
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("20060102")
+	_, err := store.Import(ctx, []database.Task{
+		{ID: "1", Date: tomorrow, Title: "Разовая"},
+		{ID: "2", Date: tomorrow, Title: "Повторяющаяся", Repeat: "d 3"},
+		// Правило, сохраненное в обход проверки, например старой версией программы
+		{ID: "3", Date: tomorrow, Title: "Испорченная", Repeat: "x 1"},
+	}, database.ConflictSkip)
+	require.NoError(t, err)
+	srv, err := server.New(testConfig(), server.WithStore(store))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	res := doJSON(t, h, http.MethodPost, "/api/task/done?id=1", "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	_, err = store.Get(ctx, "1")
+	assert.ErrorIs(t, err, database.ErrTaskNotFound, "разовая задача удаляется")
+
+	res = doJSON(t, h, http.MethodPost, "/api/task/done?id=2", "", nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	task, err := store.Get(ctx, "2")
+	require.NoError(t, err)
+	assert.Equal(t, time.Now().AddDate(0, 0, 4).Format("20060102"), task.Date, "повторяющаяся задача переносится на следующую дату")
+
+	var out struct {
+		Error string `json:"error"`
+	}
+	res = doJSON(t, h, http.MethodPost, "/api/task/done?id=3", "", nil, &out)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Contains(t, out.Error, "x 1")
+
+	res = doJSON(t, h, http.MethodPost, "/api/task/done?id=42", "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestAuth(t *testing.T) {
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	h := srv.Handler
+
+	res := doJSON(t, h, http.MethodGet, "/api/tasks", "", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodPost, "/api/signin", `{"password":"не тот"}`, nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodPost, "/api/signin", `{"password":"секрет"}`, nil, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = doJSON(t, h, http.MethodGet, "/api/tasks", "", res.Cookies(), nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"3code/config"
+)
+
+// Имена файлов самоподписанного сертификата в директории TODO_TLS_DEV_DIR
+const (
+	devCertFile = "dev-cert.pem"
+	devKeyFile  = "dev-key.pem"
+)
+
+// devCertTTL - срок действия самоподписанного сертификата. За devCertRenewBefore до истечения он создается заново.
+const (
+	devCertTTL         = 365 * 24 * time.Hour
+	devCertRenewBefore = 7 * 24 * time.Hour
+)
+
+// modernCipherSuites - шифры TLS 1.2 с обменом ключами ECDHE и AEAD-шифрованием. Для TLS 1.3 Go выбирает шифры сам.
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// newTLSConfig собирает настройки TLS: загружает сертификат из файлов или создает самоподписанный в режиме разработки.
+func newTLSConfig(cfg config.Server) (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if cfg.TLSDev {
+		cert, err = devCertificate(cfg.TLSDevDir)
+	} else {
+		cert, err = tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("функция newTLSConfig: ошибка загрузки сертификата: %w", err)
+	}
+
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.TLSMinVersion == "1.3" {
+		tlsCfg.MinVersion = tls.VersionTLS13
+	}
+	if cfg.TLSCiphers != "default" {
+		tlsCfg.CipherSuites = modernCipherSuites
+	}
+	return tlsCfg, nil
+}
+
+// devCertificate возвращает самоподписанный сертификат для localhost из директории dir
+// или создает новый, если его нет, он поврежден или скоро истекает.
+func devCertificate(dir string) (tls.Certificate, error) {
+	certPath, keyPath := filepath.Join(dir, devCertFile), filepath.Join(dir, devKeyFile)
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err == nil && cert.Leaf == nil {
+		// В старых версиях Go LoadX509KeyPair не заполняет Leaf
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	}
+	if err == nil && time.Until(cert.Leaf.NotAfter) > devCertRenewBefore {
+		slog.Info("Используется сохраненный самоподписанный сертификат", "file", certPath, "not_after", cert.Leaf.NotAfter)
+		return cert, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Сохраненный самоподписанный сертификат не подходит, создаем новый", "file", certPath, "error", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("функция devCertificate: ошибка создания директории %s: %w", dir, err)
+	}
+	certPEM, keyPEM, err := generateDevCertificate(time.Now())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("функция devCertificate: ошибка записи сертификата: %w", err)
+	}
+	// Закрытый ключ доступен только владельцу
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("функция devCertificate: ошибка записи ключа: %w", err)
+	}
+	slog.Info("Создан самоподписанный сертификат для разработки", "file", certPath)
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// generateDevCertificate создает ключ ECDSA P-256 и самоподписанный сертификат для localhost, 127.0.0.1 и ::1.
+func generateDevCertificate(now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("функция generateDevCertificate: ошибка создания ключа: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("функция generateDevCertificate: ошибка создания серийного номера: %w", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"3code dev"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(devCertTTL),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("функция generateDevCertificate: ошибка создания сертификата: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("функция generateDevCertificate: ошибка сериализации ключа: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// redirectToHTTPS перенаправляет запрос на тот же адрес по HTTPS на порту port.
+// Используется код 308, чтобы браузер повторил POST и PUT с тем же методом и телом.
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]") // IPv6-адрес без порта приходит в квадратных скобках
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
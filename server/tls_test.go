@@ -0,0 +1,145 @@
+package server_test
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"3code/database"
+	"3code/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevCertificateIsCached(t *testing.T) {
+	cfg := testConfig()
+	cfg.TLSDev = true
+	cfg.TLSDevDir = filepath.Join(t.TempDir(), "certs")
+
+	_, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	first, err := os.ReadFile(filepath.Join(cfg.TLSDevDir, "dev-cert.pem"))
+	require.NoError(t, err)
+
+	_, err = server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	second, err := os.ReadFile(filepath.Join(cfg.TLSDevDir, "dev-cert.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "сохраненный сертификат используется повторно")
+
+	// Поврежденный сертификат создается заново
+	require.NoError(t, os.WriteFile(filepath.Join(cfg.TLSDevDir, "dev-cert.pem"), []byte("мусор"), 0644))
+	_, err = server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+	third, err := os.ReadFile(filepath.Join(cfg.TLSDevDir, "dev-cert.pem"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(third), "-----BEGIN CERTIFICATE-----"))
+}
+
+func TestServeTLS(t *testing.T) {
+	cfg := testConfig()
+	cfg.Password = "секрет"
+	cfg.Port = freePort(t)
+	cfg.TLSDev = true
+	cfg.TLSDevDir = t.TempDir()
+	cfg.TLSMinVersion = "1.2"
+	cfg.TLSCiphers = "modern"
+	cfg.HTTP2 = true
+	cfg.RedirectPort = freePort(t)
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	base := "https://127.0.0.1:" + cfg.Port
+
+	var res *http.Response
+	require.Eventually(t, func() bool {
+		res, err = client.Get(base + "/healthz")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 2, res.ProtoMajor, "по TLS используется HTTP/2")
+
+	// Кука с токеном по HTTPS помечается как Secure
+	res, err = client.Post(base+"/api/signin", "application/json", strings.NewReader(`{"password":"секрет"}`))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NotEmpty(t, res.Cookies())
+	assert.True(t, res.Cookies()[0].Secure)
+
+	// Порт перенаправления отправляет на HTTPS с сохранением пути и параметров
+	require.Eventually(t, func() bool {
+		res, err = client.Get("http://127.0.0.1:" + cfg.RedirectPort + "/api/tasks?search=milk&limit=5")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	res.Body.Close()
+	assert.Equal(t, http.StatusPermanentRedirect, res.StatusCode)
+	assert.Equal(t, base+"/api/tasks?search=milk&limit=5", res.Header.Get("Location"))
+}
+
+func TestSecondSignalClosesRedirect(t *testing.T) {
+	cfg := testConfig()
+	cfg.Port = freePort(t)
+	cfg.TLSDev = true
+	cfg.TLSDevDir = t.TempDir()
+	cfg.TLSMinVersion = "1.2"
+	cfg.TLSCiphers = "modern"
+	cfg.RedirectPort = freePort(t)
+	cfg.ReadTimeout = time.Minute
+	cfg.ShutdownTimeout = time.Minute
+	srv, err := server.New(cfg, server.WithStore(database.NewMemoryStore()))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(context.Background()) }()
+
+	// Недописанный запрос к порту перенаправления держит его корректную остановку до таймаута
+	var conn net.Conn
+	require.Eventually(t, func() bool {
+		conn, err = net.Dial("tcp", "127.0.0.1:"+cfg.RedirectPort)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n")
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	srv.StopChan <- syscall.SIGTERM
+	select {
+	case <-done:
+		t.Fatal("сервер не должен остановиться, пока соединение с портом перенаправления активно")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	srv.StopChan <- syscall.SIGINT
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("повторный сигнал должен закрыть и перенаправление на HTTPS")
+	}
+}